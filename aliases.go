@@ -0,0 +1,62 @@
+package bgpstuff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// WithFieldAliases lets the client read responses from deployments that
+// name fields differently. aliases maps the name a server uses to the name
+// this package expects, such as {"Prefix": "Route"}, and is applied to
+// object keys at every depth, including the "Response" envelope.
+func WithFieldAliases(aliases map[string]string) Option {
+	return func(c *Client) {
+		if len(aliases) == 0 {
+			c.aliases = nil
+			return
+		}
+		c.aliases = make(map[string]string, len(aliases))
+		for from, to := range aliases {
+			c.aliases[from] = to
+		}
+	}
+}
+
+// aliasDecoder wraps decode so the body has its keys renamed first. The
+// body is read in full, so large responses lose the benefit of streaming.
+func (c *Client) aliasDecoder(decode func(io.Reader) error) func(io.Reader) error {
+	return func(r io.Reader) error {
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		b, err := json.Marshal(renameKeys(v, c.aliases))
+		if err != nil {
+			return err
+		}
+		return decode(bytes.NewReader(b))
+	}
+}
+
+func renameKeys(v interface{}, aliases map[string]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if to, ok := aliases[key]; ok {
+				key = to
+			}
+			out[key] = renameKeys(value, aliases)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = renameKeys(v[i], aliases)
+		}
+		return v
+	}
+	return v
+}
@@ -0,0 +1,37 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"testing"
+)
+
+func TestWithFieldAliases(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/route/1.1.1.1":
+			fmt.Fprint(w, `{"Data":{"Prefix":"1.1.1.0/24"}}`)
+		case "/totals":
+			fmt.Fprint(w, `{"Data":{"Totals":{"V4":900000,"V6":180000}}}`)
+		case "/invalids":
+			fmt.Fprint(w, `{"Data":{"Invalids":[{"ASN":"64501","Prefixes":["192.0.2.0/24"]}]}}`)
+		}
+	}, WithFieldAliases(map[string]string{"Data": "Response", "Prefix": "Route", "V4": "Ipv4", "V6": "Ipv6"}))
+
+	route, err := c.GetRoute("1.1.1.1")
+	if err != nil || route.String() != "1.1.1.0/24" {
+		t.Errorf("Got route: %v %v, Want: 1.1.1.0/24", route, err)
+	}
+	v4, v6, err := c.GetTotals()
+	if err != nil || v4 != 900000 || v6 != 180000 {
+		t.Errorf("Got totals: %d %d %v, Want: 900000 180000", v4, v6, err)
+	}
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetInvalidOrigins(netip.MustParsePrefix("192.0.2.0/24")); len(got) != 1 {
+		t.Errorf("Got: %v, Want AS64501", got)
+	}
+}
@@ -0,0 +1,93 @@
+package altsource
+
+import (
+	"sort"
+	"sync"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+// Backend is a named source of answers for an Aggregator, such as the live
+// or test API, or a self-hosted server.
+type Backend struct {
+	Name     string
+	Lookuper bgpstuff.Lookuper
+}
+
+// Aggregator asks several backends the same question and reports whether
+// they agree. It is for API operators checking their instances against each
+// other, and for users checking their own deployment against the public
+// one.
+type Aggregator struct {
+	backends []Backend
+}
+
+// NewAggregator returns an Aggregator asking backends, which should have
+// distinct names.
+func NewAggregator(backends ...Backend) *Aggregator {
+	return &Aggregator{backends: append([]Backend(nil), backends...)}
+}
+
+// AnswerGroup is an answer and the backends that gave it.
+type AnswerGroup struct {
+	Route    string
+	Origin   int
+	Backends []string
+}
+
+// Aggregate holds what every backend said about an IP.
+type Aggregate struct {
+	IP string
+	// Answers are by backend name.
+	Answers map[string]Answer
+	// Groups are the distinct answers, those given by the most backends
+	// first, leaving out backends that failed.
+	Groups []AnswerGroup
+	// Failed are the backends whose lookups failed, in the order they
+	// were given.
+	Failed []string
+	// Agree is true if every backend answered, and all gave the same route
+	// and origin.
+	Agree bool
+}
+
+// Compare looks up the route and origin of ip with every backend at once.
+func (a *Aggregator) Compare(ip string, opts ...bgpstuff.CallOption) Aggregate {
+	answers := make([]Answer, len(a.backends))
+	var wg sync.WaitGroup
+	for i, b := range a.backends {
+		wg.Add(1)
+		go func(i int, l bgpstuff.Lookuper) {
+			defer wg.Done()
+			answers[i] = answer(l, ip, opts)
+		}(i, b.Lookuper)
+	}
+	wg.Wait()
+
+	agg := Aggregate{IP: ip, Answers: make(map[string]Answer, len(a.backends))}
+	for i, b := range a.backends {
+		ans := answers[i]
+		agg.Answers[b.Name] = ans
+		if ans.Err != nil {
+			agg.Failed = append(agg.Failed, b.Name)
+			continue
+		}
+		found := false
+		for j := range agg.Groups {
+			g := &agg.Groups[j]
+			if g.Route == ans.Route && g.Origin == ans.Origin {
+				g.Backends = append(g.Backends, b.Name)
+				found = true
+				break
+			}
+		}
+		if !found {
+			agg.Groups = append(agg.Groups, AnswerGroup{Route: ans.Route, Origin: ans.Origin, Backends: []string{b.Name}})
+		}
+	}
+	sort.SliceStable(agg.Groups, func(i, j int) bool {
+		return len(agg.Groups[i].Backends) > len(agg.Groups[j].Backends)
+	})
+	agg.Agree = len(agg.Failed) == 0 && len(agg.Groups) == 1
+	return agg
+}
@@ -0,0 +1,166 @@
+package altsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+func TestRIPEstat(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path + "?" + r.URL.Query().Get("resource") {
+		case "/network-info/data.json?1.1.1.1":
+			fmt.Fprint(w, `{"data":{"asns":["13335"],"prefix":"1.1.1.0/24"}}`)
+		case "/network-info/data.json?19.1.1.1":
+			fmt.Fprint(w, `{"data":{"asns":[],"prefix":null}}`)
+		case "/as-overview/data.json?AS13335":
+			fmt.Fprint(w, `{"data":{"holder":"CLOUDFLARENET - Cloudflare, Inc.","announced":true}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	r := NewRIPEstat()
+	r.BaseURL = srv.URL
+
+	route, err := r.GetRoute("1.1.1.1")
+	if err != nil || route.String() != "1.1.1.0/24" {
+		t.Errorf("Got route: %v %v, Want: 1.1.1.0/24", route, err)
+	}
+	origin, err := r.GetOrigin("1.1.1.1")
+	if err != nil || origin != 13335 {
+		t.Errorf("Got origin: %d %v, Want: 13335", origin, err)
+	}
+	if route, err := r.GetRoute("19.1.1.1"); err != nil || route != nil {
+		t.Errorf("Got route: %v %v, Want none", route, err)
+	}
+	name, err := r.GetASName(13335)
+	if err != nil || name != "CLOUDFLARENET" {
+		t.Errorf("Got name: %q %v, Want: CLOUDFLARENET", name, err)
+	}
+	if _, err := r.GetOrigin("not an ip"); !errors.Is(err, bgpstuff.ErrInvalidIP) {
+		t.Errorf("Expected ErrInvalidIP, got: %v", err)
+	}
+}
+
+func TestCymru(t *testing.T) {
+	t.Parallel()
+	records := map[string][]string{
+		"1.1.1.1.origin.asn.cymru.com": {
+			"13335 | 1.1.0.0/16 | AU | apnic | 2011-08-11",
+			"13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11",
+		},
+		"1.1.1.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.7.4.6.0.6.2.origin6.asn.cymru.com": {
+			"13335 | 2606:4700::/32 | US | arin | 2011-11-01",
+		},
+		"AS13335.asn.cymru.com": {"13335 | US | arin | 2010-07-14 | CLOUDFLARENET, US"},
+	}
+	c := &Cymru{lookupTXT: func(_ context.Context, name string) ([]string, error) {
+		if r, ok := records[name]; ok {
+			return r, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}}
+
+	route, err := c.GetRoute("1.1.1.1")
+	if err != nil || route.String() != "1.1.1.0/24" {
+		t.Errorf("Got route: %v %v, Want: 1.1.1.0/24", route, err)
+	}
+	origin, err := c.GetOrigin("2606:4700::1111")
+	if err != nil || origin != 13335 {
+		t.Errorf("Got origin: %d %v, Want: 13335", origin, err)
+	}
+	if origin, err := c.GetOrigin("19.1.1.1"); err != nil || origin != 0 {
+		t.Errorf("Got origin: %d %v, Want none", origin, err)
+	}
+	name, err := c.GetASName(13335)
+	if err != nil || name != "CLOUDFLARENET" {
+		t.Errorf("Got name: %q %v, Want: CLOUDFLARENET", name, err)
+	}
+}
+
+// staticLookuper answers every IP with the same route and origin.
+type staticLookuper struct {
+	route  string
+	origin int
+	err    error
+}
+
+func (s staticLookuper) GetRoute(string, ...bgpstuff.CallOption) (*net.IPNet, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	p := netip.MustParsePrefix(s.route)
+	return &net.IPNet{IP: p.Addr().AsSlice(), Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen())}, nil
+}
+
+func (s staticLookuper) GetOrigin(string, ...bgpstuff.CallOption) (int, error) {
+	return s.origin, s.err
+}
+
+func (s staticLookuper) GetASName(int, ...bgpstuff.CallOption) (string, error) {
+	return "", s.err
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	a := staticLookuper{route: "1.1.1.0/24", origin: 13335}
+	b := staticLookuper{route: "1.1.0.0/16", origin: 13335}
+	down := staticLookuper{err: errors.New("down")}
+
+	if cmp := Compare("1.1.1.1", a, a); !cmp.Agree {
+		t.Errorf("Got: %+v, Want agreement", cmp)
+	}
+	if cmp := Compare("1.1.1.1", a, b); cmp.Agree || cmp.Alternate.Route != "1.1.0.0/16" {
+		t.Errorf("Got: %+v, Want disagreement on the route", cmp)
+	}
+	if cmp := Compare("1.1.1.1", a, down); cmp.Agree || cmp.Alternate.Err == nil {
+		t.Errorf("Got: %+v, Want the alternate's error", cmp)
+	}
+
+	origin, err := Fallback(down, b).GetOrigin("1.1.1.1")
+	if err != nil || origin != 13335 {
+		t.Errorf("Got: %d %v, Want the secondary's answer", origin, err)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	t.Parallel()
+	a := staticLookuper{route: "1.1.1.0/24", origin: 13335}
+	b := staticLookuper{route: "1.1.0.0/16", origin: 13335}
+	down := staticLookuper{err: errors.New("down")}
+
+	agg := NewAggregator(
+		Backend{Name: "test", Lookuper: b},
+		Backend{Name: "live", Lookuper: a},
+		Backend{Name: "self", Lookuper: a},
+		Backend{Name: "lab", Lookuper: down},
+	).Compare("1.1.1.1")
+	if agg.Agree {
+		t.Errorf("Got: %+v, Want disagreement", agg)
+	}
+	want := []AnswerGroup{
+		{Route: "1.1.1.0/24", Origin: 13335, Backends: []string{"live", "self"}},
+		{Route: "1.1.0.0/16", Origin: 13335, Backends: []string{"test"}},
+	}
+	if !reflect.DeepEqual(agg.Groups, want) {
+		t.Errorf("Got groups: %+v, Want: %+v", agg.Groups, want)
+	}
+	if len(agg.Failed) != 1 || agg.Failed[0] != "lab" || agg.Answers["lab"].Err == nil {
+		t.Errorf("Got failed: %v, Want: [lab]", agg.Failed)
+	}
+
+	agg = NewAggregator(Backend{Name: "live", Lookuper: a}, Backend{Name: "self", Lookuper: a}).Compare("1.1.1.1")
+	if !agg.Agree {
+		t.Errorf("Got: %+v, Want agreement", agg)
+	}
+}
@@ -0,0 +1,98 @@
+package altsource
+
+import (
+	"net"
+	"sync"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+// Answer is what one source said about an IP.
+type Answer struct {
+	// Route is the covering prefix, or empty if there is none.
+	Route  string
+	Origin int
+	// Err is set if either lookup failed.
+	Err error
+}
+
+// Comparison holds the answers two sources gave for an IP.
+type Comparison struct {
+	IP        string
+	Primary   Answer
+	Alternate Answer
+	// Agree is true if both sources answered and gave the same route and
+	// origin.
+	Agree bool
+}
+
+// Compare looks up the route and origin of ip with both sources at once and
+// reports whether they agree. It is meant for checking bgpstuff.net's data
+// against another source.
+func Compare(ip string, primary, alternate bgpstuff.Lookuper, opts ...bgpstuff.CallOption) Comparison {
+	cmp := Comparison{IP: ip}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		cmp.Primary = answer(primary, ip, opts)
+	}()
+	go func() {
+		defer wg.Done()
+		cmp.Alternate = answer(alternate, ip, opts)
+	}()
+	wg.Wait()
+
+	cmp.Agree = cmp.Primary.Err == nil && cmp.Alternate.Err == nil &&
+		cmp.Primary.Route == cmp.Alternate.Route &&
+		cmp.Primary.Origin == cmp.Alternate.Origin
+	return cmp
+}
+
+func answer(l bgpstuff.Lookuper, ip string, opts []bgpstuff.CallOption) Answer {
+	var a Answer
+	route, err := l.GetRoute(ip, opts...)
+	if err != nil {
+		a.Err = err
+		return a
+	}
+	if route != nil {
+		a.Route = route.String()
+	}
+	a.Origin, a.Err = l.GetOrigin(ip, opts...)
+	return a
+}
+
+// Fallback returns a Lookuper that asks primary first, and asks secondary
+// whenever primary fails.
+func Fallback(primary, secondary bgpstuff.Lookuper) bgpstuff.Lookuper {
+	return fallback{primary, secondary}
+}
+
+type fallback struct {
+	primary, secondary bgpstuff.Lookuper
+}
+
+func (f fallback) GetRoute(ip string, opts ...bgpstuff.CallOption) (*net.IPNet, error) {
+	route, err := f.primary.GetRoute(ip, opts...)
+	if err != nil {
+		return f.secondary.GetRoute(ip, opts...)
+	}
+	return route, nil
+}
+
+func (f fallback) GetOrigin(ip string, opts ...bgpstuff.CallOption) (int, error) {
+	origin, err := f.primary.GetOrigin(ip, opts...)
+	if err != nil {
+		return f.secondary.GetOrigin(ip, opts...)
+	}
+	return origin, nil
+}
+
+func (f fallback) GetASName(asn int, opts ...bgpstuff.CallOption) (string, error) {
+	name, err := f.primary.GetASName(asn, opts...)
+	if err != nil {
+		return f.secondary.GetASName(asn, opts...)
+	}
+	return name, nil
+}
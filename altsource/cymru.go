@@ -0,0 +1,162 @@
+package altsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+// Cymru answers lookups from Team Cymru's IP to ASN DNS service.
+type Cymru struct {
+	// Resolver looks up the TXT records. It defaults to the system
+	// resolver.
+	Resolver *net.Resolver
+
+	// lookupTXT is replaced in tests.
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+var _ bgpstuff.Lookuper = (*Cymru)(nil)
+
+// NewCymru returns a Cymru adapter using the system resolver.
+func NewCymru() *Cymru {
+	return &Cymru{Resolver: net.DefaultResolver}
+}
+
+// GetRoute returns the prefix Team Cymru sees covering ip, or nil if there
+// is none.
+func (c *Cymru) GetRoute(ip string, opts ...bgpstuff.CallOption) (*net.IPNet, error) {
+	fields, err := c.origin(ip, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cymru route lookup for %q: %w", ip, err)
+	}
+	if fields == nil {
+		return nil, nil
+	}
+	_, prefix, err := net.ParseCIDR(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("cymru route lookup for %q: %w", ip, err)
+	}
+	return prefix, nil
+}
+
+// GetOrigin returns the AS originating the prefix covering ip, or 0 if
+// there is none. If several ASes originate it, the first is returned.
+func (c *Cymru) GetOrigin(ip string, opts ...bgpstuff.CallOption) (int, error) {
+	fields, err := c.origin(ip, opts)
+	if err != nil {
+		return 0, fmt.Errorf("cymru origin lookup for %q: %w", ip, err)
+	}
+	if fields == nil {
+		return 0, nil
+	}
+	asn, err := strconv.Atoi(strings.Fields(fields[0])[0])
+	if err != nil {
+		return 0, fmt.Errorf("cymru origin lookup for %q: %w", ip, err)
+	}
+	return asn, nil
+}
+
+// GetASName returns the short name of asn, such as "CLOUDFLARENET".
+func (c *Cymru) GetASName(asn int, opts ...bgpstuff.CallOption) (string, error) {
+	// "13335 | US | arin | 2010-07-14 | CLOUDFLARENET, US"
+	records, err := c.txt(bgpstuff.CallContext(opts...), fmt.Sprintf("AS%d.asn.cymru.com", asn))
+	if err != nil {
+		return "", fmt.Errorf("cymru asname lookup for \"%d\": %w", asn, err)
+	}
+	for _, record := range records {
+		fields := splitRecord(record)
+		if len(fields) < 5 {
+			continue
+		}
+		name := fields[4]
+		if i := strings.LastIndex(name, ", "); i > 0 {
+			name = name[:i]
+		}
+		return name, nil
+	}
+	return "", nil
+}
+
+// origin returns the fields of the most specific origin record for ip, or
+// nil if there is none. Records look like
+// "13335 | 1.1.1.0/24 | AU | apnic | 2011-08-11".
+func (c *Cymru) origin(ip string, opts []bgpstuff.CallOption) ([]string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, bgpstuff.ErrInvalidIP
+	}
+	records, err := c.txt(bgpstuff.CallContext(opts...), originName(addr.Unmap()))
+	if err != nil {
+		return nil, err
+	}
+
+	var best []string
+	bestBits := -1
+	for _, record := range records {
+		fields := splitRecord(record)
+		if len(fields) < 2 || len(strings.Fields(fields[0])) == 0 {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(fields[1])
+		if err != nil {
+			continue
+		}
+		if prefix.Bits() > bestBits {
+			best, bestBits = fields, prefix.Bits()
+		}
+	}
+	return best, nil
+}
+
+// txt looks up the TXT records for name. A name that doesn't exist has no
+// records rather than being an error.
+func (c *Cymru) txt(ctx context.Context, name string) ([]string, error) {
+	lookup := c.lookupTXT
+	if lookup == nil {
+		resolver := c.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		lookup = resolver.LookupTXT
+	}
+	records, err := lookup(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	return records, err
+}
+
+// originName returns the name to query for the origin of addr.
+func originName(addr netip.Addr) string {
+	if addr.Is4() {
+		b := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", b[3], b[2], b[1], b[0])
+	}
+	const hex = "0123456789abcdef"
+	b := addr.As16()
+	var name strings.Builder
+	for i := len(b) - 1; i >= 0; i-- {
+		name.WriteByte(hex[b[i]&0xf])
+		name.WriteByte('.')
+		name.WriteByte(hex[b[i]>>4])
+		name.WriteByte('.')
+	}
+	name.WriteString("origin6.asn.cymru.com")
+	return name.String()
+}
+
+func splitRecord(record string) []string {
+	fields := strings.Split(record, "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields
+}
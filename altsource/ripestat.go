@@ -0,0 +1,138 @@
+// Package altsource has bgpstuff.Lookuper implementations backed by data
+// sources other than bgpstuff.net, and helpers to compare their answers
+// with the client's or to fall back to them.
+package altsource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+const ripestatAPI = "https://stat.ripe.net/data"
+
+// RIPEstat answers lookups from the RIPEstat data API.
+type RIPEstat struct {
+	// BaseURL is the data API to use, ending in "/data".
+	BaseURL string
+	// HTTPClient makes the requests.
+	HTTPClient *http.Client
+	// SourceApp identifies the caller to RIPEstat, as they ask.
+	SourceApp string
+}
+
+var _ bgpstuff.Lookuper = (*RIPEstat)(nil)
+
+// NewRIPEstat returns a RIPEstat adapter using the public API.
+func NewRIPEstat() *RIPEstat {
+	return &RIPEstat{
+		BaseURL:    ripestatAPI,
+		HTTPClient: &http.Client{Timeout: 8 * time.Second},
+		SourceApp:  "go-bgpstuff.net",
+	}
+}
+
+type networkInfo struct {
+	Data struct {
+		ASNs   []string `json:"asns"`
+		Prefix string   `json:"prefix"`
+	} `json:"data"`
+}
+
+type asOverview struct {
+	Data struct {
+		Holder    string `json:"holder"`
+		Announced bool   `json:"announced"`
+	} `json:"data"`
+}
+
+// GetRoute returns the prefix RIPEstat sees covering ip, or nil if there is
+// none.
+func (r *RIPEstat) GetRoute(ip string, opts ...bgpstuff.CallOption) (*net.IPNet, error) {
+	info, err := r.networkInfo(ip, opts)
+	if err != nil {
+		return nil, fmt.Errorf("ripestat route lookup for %q: %w", ip, err)
+	}
+	if info.Data.Prefix == "" {
+		return nil, nil
+	}
+	_, prefix, err := net.ParseCIDR(info.Data.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("ripestat route lookup for %q: %w", ip, err)
+	}
+	return prefix, nil
+}
+
+// GetOrigin returns the AS originating the prefix covering ip, or 0 if
+// there is none. If several ASes originate it, the first is returned.
+func (r *RIPEstat) GetOrigin(ip string, opts ...bgpstuff.CallOption) (int, error) {
+	info, err := r.networkInfo(ip, opts)
+	if err != nil {
+		return 0, fmt.Errorf("ripestat origin lookup for %q: %w", ip, err)
+	}
+	if len(info.Data.ASNs) == 0 {
+		return 0, nil
+	}
+	asn, err := strconv.Atoi(info.Data.ASNs[0])
+	if err != nil {
+		return 0, fmt.Errorf("ripestat origin lookup for %q: %w", ip, err)
+	}
+	return asn, nil
+}
+
+// GetASName returns the short name of asn, such as "CLOUDFLARENET".
+func (r *RIPEstat) GetASName(asn int, opts ...bgpstuff.CallOption) (string, error) {
+	var overview asOverview
+	if err := r.get("as-overview", fmt.Sprintf("AS%d", asn), opts, &overview); err != nil {
+		return "", fmt.Errorf("ripestat asname lookup for \"%d\": %w", asn, err)
+	}
+	// The holder is given as "NAME - Organisation".
+	name, _, _ := strings.Cut(overview.Data.Holder, " - ")
+	return name, nil
+}
+
+func (r *RIPEstat) networkInfo(ip string, opts []bgpstuff.CallOption) (*networkInfo, error) {
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return nil, bgpstuff.ErrInvalidIP
+	}
+	var info networkInfo
+	if err := r.get("network-info", ip, opts, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// get fetches a RIPEstat data call for resource into v.
+func (r *RIPEstat) get(call, resource string, opts []bgpstuff.CallOption, v interface{}) error {
+	q := url.Values{"resource": {resource}}
+	if r.SourceApp != "" {
+		q.Set("sourceapp", r.SourceApp)
+	}
+	uri := fmt.Sprintf("%s/%s/data.json?%s", r.BaseURL, call, q.Encode())
+
+	req, err := http.NewRequestWithContext(bgpstuff.CallContext(opts...), "GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status: %s (%d)", http.StatusText(res.StatusCode), res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,38 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/api"
+)
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+	body := `{"Response":{"Action":"roa","Route":"1.1.1.0/24","Origin":"13335","ROA":"VALID",` +
+		`"Invalids":[{"ASN":"64501","Prefixes":["192.0.2.0/24"]}],"Exists":true}}`
+	var resp api.Response
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	d := resp.Data
+	if d.Action != "roa" || d.Origin != 13335 || d.ROA != "VALID" || !d.Exists {
+		t.Errorf("Got: %+v", d)
+	}
+	if len(d.Invalids) != 1 || d.Invalids[0].ASN != 64501 {
+		t.Errorf("Got invalids: %+v, Want AS64501", d.Invalids)
+	}
+
+	// Encoding gives back the same wire format.
+	b, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again api.Response
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatal(err)
+	}
+	if again.Data.Origin != 13335 || again.Data.Invalids[0].ASN != 64501 {
+		t.Errorf("Round trip lost data: %s", b)
+	}
+}
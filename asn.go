@@ -0,0 +1,51 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ASN is an autonomous system number.
+type ASN uint32
+
+// String returns the ASN in the usual AS1234 form.
+func (a ASN) String() string {
+	return fmt.Sprintf("AS%d", uint32(a))
+}
+
+// StringDot returns the ASN in asdot notation (RFC 5396), as some router
+// and IRR tooling still shows 4-byte ASNs: AS3.3356 for 199964, and the
+// usual AS1234 form for 2-byte ASNs.
+func (a ASN) StringDot() string {
+	if a <= 0xffff {
+		return a.String()
+	}
+	return fmt.Sprintf("AS%d.%d", uint32(a)>>16, uint32(a)&0xffff)
+}
+
+// ParseASN parses an AS number with or without an "AS" prefix, such as
+// "AS13335", "as13335" or "13335". 4-byte ASNs may also be in asdot
+// notation, such as "3.3356" or "AS3.3356".
+func ParseASN(s string) (ASN, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && strings.EqualFold(s[:2], "AS") {
+		s = s[2:]
+	}
+	if high, low, ok := strings.Cut(s, "."); ok {
+		h, err := strconv.ParseUint(high, 10, 16)
+		if err != nil {
+			return 0, ErrInvalidASN
+		}
+		l, err := strconv.ParseUint(low, 10, 16)
+		if err != nil {
+			return 0, ErrInvalidASN
+		}
+		return ASN(h<<16 | l), nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidASN
+	}
+	return ASN(n), nil
+}
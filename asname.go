@@ -0,0 +1,120 @@
+package bgpstuff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ASName is an AS name as the registries record it, such as "GOOGLE, US"
+// or "CLOUDFLARENET - Cloudflare, Inc., US". Its methods pick out the parts
+// so that names from different registries can be shown consistently:
+//
+//	bgpstuff.ASName(name).Format(bgpstuff.NameFormat{Org: true})
+type ASName string
+
+// NameFormat says how Format shows an AS name.
+type NameFormat struct {
+	// Org shows the organisation name rather than the AS handle, where the
+	// registry gives both.
+	Org bool
+	// TitleCase changes words in capitals, such as "GOOGLE", to "Google".
+	// Words of three letters or fewer are left alone, as they are mostly
+	// acronyms such as "LLC" or "IBM".
+	TitleCase bool
+	// KeepCountry leaves the trailing country code on.
+	KeepCountry bool
+}
+
+// Country returns the country code at the end of the name, such as "US",
+// or "" if there is none.
+func (n ASName) Country() string {
+	_, cc := n.splitCountry()
+	return cc
+}
+
+// ShortName returns the AS handle, such as "CLOUDFLARENET", without the
+// organisation or country.
+func (n ASName) ShortName() string {
+	name, _ := n.splitCountry()
+	if handle, _, ok := strings.Cut(name, " - "); ok {
+		return strings.TrimSpace(handle)
+	}
+	return name
+}
+
+// OrgName returns the organisation, such as "Cloudflare, Inc.", without
+// the country. Names that have no organisation part return the AS handle.
+func (n ASName) OrgName() string {
+	name, _ := n.splitCountry()
+	if _, org, ok := strings.Cut(name, " - "); ok {
+		return strings.TrimSpace(org)
+	}
+	return name
+}
+
+// Format returns the name as f says.
+func (n ASName) Format(f NameFormat) string {
+	name := n.ShortName()
+	if f.Org {
+		name = n.OrgName()
+	}
+	if f.TitleCase {
+		name = titleCase(name)
+	}
+	if cc := n.Country(); f.KeepCountry && cc != "" {
+		name += ", " + cc
+	}
+	return name
+}
+
+// splitCountry splits a trailing ", CC" off the name.
+func (n ASName) splitCountry() (string, string) {
+	name := strings.TrimSpace(string(n))
+	i := strings.LastIndex(name, ",")
+	if i < 0 {
+		return name, ""
+	}
+	cc := strings.TrimSpace(name[i+1:])
+	if len(cc) != 2 || !isUpper(cc) {
+		return name, ""
+	}
+	return strings.TrimSpace(name[:i]), cc
+}
+
+// titleCase title-cases the words of s that are all capitals and longer
+// than three letters.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		letters := 0
+		for _, r := range w {
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+		if letters <= 3 || !isUpper(w) {
+			continue
+		}
+		runes := []rune(strings.ToLower(w))
+		for j, r := range runes {
+			if unicode.IsLetter(r) {
+				runes[j] = unicode.ToUpper(r)
+				break
+			}
+		}
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// isUpper reports whether s has letters and they are all capitals.
+func isUpper(s string) bool {
+	letters := false
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		letters = letters || unicode.IsLetter(r)
+	}
+	return letters
+}
@@ -0,0 +1,240 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SegmentType is the kind of an AS path segment.
+type SegmentType int
+
+// Segment types. bgpstuff.net does not report confederation segments, so
+// only sequences and sets appear.
+const (
+	SegmentSequence SegmentType = iota // ASNs in the order the route passed through them
+	SegmentSet                         // an unordered set of ASNs left by aggregation
+)
+
+// ASPathSegment is a run of ASNs of a single segment type.
+type ASPathSegment struct {
+	Type SegmentType
+	ASNs []ASN
+}
+
+// ASPath is an AS path broken into its segments. bgpstuff.net reports the
+// AS set apart from the path, with no position, so an ASPath holds at most
+// one sequence followed by at most one set. The set is always trailing and
+// says nothing about where in the path aggregation happened.
+type ASPath struct {
+	Segments []ASPathSegment
+}
+
+// newASPath builds an ASPath from the path and set returned by the API,
+// appending the set after the sequence.
+func newASPath(path, set []int) *ASPath {
+	var p ASPath
+	if len(path) > 0 {
+		p.Segments = append(p.Segments, ASPathSegment{Type: SegmentSequence, ASNs: toASNs(path)})
+	}
+	if len(set) > 0 {
+		p.Segments = append(p.Segments, ASPathSegment{Type: SegmentSet, ASNs: toASNs(set)})
+	}
+	return &p
+}
+
+func toASNs(asns []int) []ASN {
+	out := make([]ASN, 0, len(asns))
+	for _, asn := range asns {
+		out = append(out, ASN(asn))
+	}
+	return out
+}
+
+// Len returns the path length as BGP best path selection counts it: each
+// ASN in a sequence counts once, and each set counts once in total.
+func (p *ASPath) Len() int {
+	n := 0
+	for _, seg := range p.Segments {
+		if seg.Type == SegmentSet {
+			n++
+			continue
+		}
+		n += len(seg.ASNs)
+	}
+	return n
+}
+
+// Origin returns the originating ASN. If the path carries an AS set the
+// origin is ambiguous, and Origin returns false.
+func (p *ASPath) Origin() (ASN, bool) {
+	if len(p.Segments) == 0 {
+		return 0, false
+	}
+	last := p.Segments[len(p.Segments)-1]
+	if last.Type != SegmentSequence || len(last.ASNs) == 0 {
+		return 0, false
+	}
+	return last.ASNs[len(last.ASNs)-1], true
+}
+
+// String returns the path the way routers display it, with sets in braces.
+func (p *ASPath) String() string {
+	parts := make([]string, 0, len(p.Segments))
+	for _, seg := range p.Segments {
+		asns := make([]string, 0, len(seg.ASNs))
+		for _, asn := range seg.ASNs {
+			asns = append(asns, fmt.Sprint(uint32(asn)))
+		}
+		if seg.Type == SegmentSet {
+			parts = append(parts, "{"+strings.Join(asns, ",")+"}")
+			continue
+		}
+		parts = append(parts, strings.Join(asns, " "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DetectPrepending returns the ASN with the longest run of consecutive
+// repeats in path, along with the length of that run. If no ASN is
+// repeated, it returns 0, 0. Ties go to the ASN closest to the origin.
+func DetectPrepending(path []int) (asn int, count int) {
+	for i := 0; i < len(path); {
+		j := i
+		for j < len(path) && path[j] == path[i] {
+			j++
+		}
+		if run := j - i; run > 1 && run >= count {
+			asn, count = path[i], run
+		}
+		i = j
+	}
+	return asn, count
+}
+
+// ContainsPoison reports whether myASN appears in path more than once with
+// other ASNs in between. Prepending myASN is not poisoning, but a path like
+// 174 65001 3356 65001 is the shape left when an operator inserts another
+// network's ASN to stop it accepting the route.
+func ContainsPoison(path []int, myASN int) bool {
+	seen := false
+	for i, asn := range path {
+		if asn != myASN {
+			continue
+		}
+		if seen && path[i-1] != myASN {
+			return true
+		}
+		seen = true
+	}
+	return false
+}
+
+// ASPathRegexp is a compiled Cisco style AS path regular expression.
+type ASPathRegexp struct {
+	re *regexp.Regexp
+}
+
+// CompileASPath compiles a Cisco style AS path regular expression. It is
+// a regular expression over the path written as space separated ASNs,
+// such as "174 3356 13335", where "_" matches the start or end of the path
+// or a delimiter between ASNs. For example "_3356_" matches any path
+// through AS3356, and "^174 .* 13335$" paths learned from AS174 and
+// originated by AS13335.
+func CompileASPath(pattern string) (*ASPathRegexp, error) {
+	var expr strings.Builder
+	for _, r := range pattern {
+		if r == '_' {
+			expr.WriteString(`(?:^|$|[ ,{}()])`)
+			continue
+		}
+		expr.WriteRune(r)
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("as path pattern %q: %w", pattern, err)
+	}
+	return &ASPathRegexp{re: re}, nil
+}
+
+// Match reports whether path matches the expression.
+func (r *ASPathRegexp) Match(path []int) bool {
+	return r.re.MatchString(joinASNs(path))
+}
+
+// MatchASPath reports whether path matches the Cisco style AS path
+// regular expression pattern, as described for CompileASPath. An invalid
+// pattern matches nothing. Use CompileASPath when matching many paths
+// against the same pattern.
+func MatchASPath(path []int, pattern string) bool {
+	re, err := CompileASPath(pattern)
+	if err != nil {
+		return false
+	}
+	return re.Match(path)
+}
+
+// ASHop is an AS on a path with its name and locale, ready for display.
+type ASHop struct {
+	ASN    ASN
+	Name   string
+	Locale string
+}
+
+// Flag returns the locale as a flag emoji, or "" if the locale is not a
+// two letter country code.
+func (h ASHop) Flag() string {
+	if len(h.Locale) != 2 {
+		return ""
+	}
+	var flag strings.Builder
+	for _, r := range strings.ToUpper(h.Locale) {
+		if r < 'A' || r > 'Z' {
+			return ""
+		}
+		flag.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return flag.String()
+}
+
+// AnnotatePath returns the hops of path with names and locales from the
+// table loaded by GetASNames. It does not query the API; hops not in the
+// table, or all of them if it is not loaded, have no name or locale.
+func (c *Client) AnnotatePath(path []int) []ASHop {
+	asinfo := c.asTable()
+	hops := make([]ASHop, 0, len(path))
+	for _, asn := range path {
+		info := asinfo[uint32(asn)]
+		hops = append(hops, ASHop{ASN: ASN(asn), Name: info.ASName, Locale: info.ASLocale})
+	}
+	return hops
+}
+
+// GetAnnotatedASPath looks up the AS path to ip and annotates it as
+// AnnotatePath does. If the AS table is not loaded, names are looked up
+// one AS at a time instead, and locales are left empty. Private, reserved
+// and documentation ASNs, which the API won't name, are left unnamed.
+func (c *Client) GetAnnotatedASPath(ip string, opts ...CallOption) ([]ASHop, error) {
+	path, _, err := c.GetASPath(ip, opts...)
+	if err != nil {
+		return nil, err
+	}
+	hops := c.AnnotatePath(path)
+	if c.asTable() != nil {
+		return hops, nil
+	}
+	public := make([]int, 0, len(path))
+	for _, asn := range path {
+		if c.validASN(asn) {
+			public = append(public, asn)
+		}
+	}
+	names, err := c.GetASNamesFor(public, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hops {
+		hops[i].Name = names[int(hops[i].ASN)]
+	}
+	return hops, nil
+}
@@ -0,0 +1,60 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnnotatePath(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+	hops, err := c.GetAnnotatedASPath("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ASHop{{ASN: 174}, {ASN: 13335, Name: "CLOUDFLARENET"}}
+	if !reflect.DeepEqual(hops, want) {
+		t.Errorf("Got: %v, Want: %v", hops, want)
+	}
+
+	// Private and documentation hops are left unnamed rather than failing
+	// the lookup.
+	private := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/aspath/1.1.1.1" {
+			fmt.Fprint(w, `{"Response":{"Action":"aspath","ASPath":["64512","174","64496","13335"],"Exists":true}}`)
+			return
+		}
+		fakeAPI(w, r)
+	})
+	hops, err = private.GetAnnotatedASPath("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []ASHop{{ASN: 64512}, {ASN: 174}, {ASN: 64496}, {ASN: 13335, Name: "CLOUDFLARENET"}}
+	if !reflect.DeepEqual(hops, want) {
+		t.Errorf("Got: %v, Want: %v", hops, want)
+	}
+
+	c.setASTable(map[uint32]ASNumName{
+		174:   {ASN: 174, ASName: "COGENT-174", ASLocale: "US"},
+		13335: {ASN: 13335, ASName: "CLOUDFLARENET", ASLocale: "US"},
+	}, nil, time.Now())
+	hops = c.AnnotatePath([]int{174, 13335, 64500})
+	want = []ASHop{
+		{ASN: 174, Name: "COGENT-174", Locale: "US"},
+		{ASN: 13335, Name: "CLOUDFLARENET", Locale: "US"},
+		{ASN: 64500},
+	}
+	if !reflect.DeepEqual(hops, want) {
+		t.Errorf("Got: %v, Want: %v", hops, want)
+	}
+	if got := hops[0].Flag(); got != "\U0001F1FA\U0001F1F8" {
+		t.Errorf("Got flag: %q, Want the US flag", got)
+	}
+	if got := hops[2].Flag(); got != "" {
+		t.Errorf("Got flag: %q, Want none", got)
+	}
+}
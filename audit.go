@@ -0,0 +1,163 @@
+package bgpstuff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a line of the audit log kept by WithAuditLog.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Endpoint is the endpoint asked, such as "origin", and Query what was
+	// asked of it, such as "1.1.1.1".
+	Endpoint string `json:"endpoint"`
+	Query    string `json:"query,omitempty"`
+	// Result summarises the answer, such as "AS13335" for an origin.
+	Result string `json:"result,omitempty"`
+	// Cached and Stale are as in Meta. Local is set when the API could not
+	// be reached and the answer was worked out from data fetched earlier,
+	// as WithLocalOriginFallback does.
+	Cached bool `json:"cached,omitempty"`
+	Stale  bool `json:"stale,omitempty"`
+	Local  bool `json:"local,omitempty"`
+	// Error is set if the lookup failed.
+	Error string `json:"error,omitempty"`
+}
+
+// WithAuditLog writes an AuditEntry for every API request the client
+// makes or answers in place of one, from its cache, a stale copy or
+// WithLocalOriginFallback, to w as a line of JSON. Lookups answered from
+// tables already loaded in memory, such as GetASName after GetASNames,
+// GetInvalid and CheckROA, are not written. Entries may be changed or
+// dropped before they are written with WithAuditRedact. Errors writing to
+// w are ignored, so a broken log never fails a lookup.
+func WithAuditLog(w io.Writer) Option {
+	return func(c *Client) {
+		if c.audit == nil {
+			c.audit = &auditLog{}
+		}
+		c.audit.w = w
+	}
+}
+
+// WithAuditRedact adds a function that is given each audit entry before it
+// is written, and may change it. If it returns false the entry is dropped.
+// Functions run in the order they were added. RedactQuery and
+// RedactResult cover the usual needs.
+func WithAuditRedact(redact func(*AuditEntry) bool) Option {
+	return func(c *Client) {
+		if c.audit == nil {
+			c.audit = &auditLog{}
+		}
+		c.audit.redact = append(c.audit.redact, redact)
+	}
+}
+
+// RedactQuery cuts IPs in an audit entry's query down to their /24 or /48,
+// so the log shows which network was looked up but not which host. The IP
+// is cut down in the entry's error too, as a failed request's error
+// carries its URL.
+func RedactQuery(e *AuditEntry) bool {
+	addr, err := netip.ParseAddr(e.Query)
+	if err != nil {
+		return true
+	}
+	bits := 24
+	if addr.Is6() {
+		bits = 48
+	}
+	p, _ := addr.Prefix(bits)
+	e.Error = strings.ReplaceAll(e.Error, e.Query, p.String())
+	e.Query = p.String()
+	return true
+}
+
+// RedactResult removes the result from an audit entry, leaving only what
+// was looked up.
+func RedactResult(e *AuditEntry) bool {
+	e.Result = ""
+	return true
+}
+
+type auditLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	redact []func(*AuditEntry) bool
+}
+
+// log writes an entry for the query made up of urls.
+func (a *auditLog) log(call *callOptions, urls []string, resp *response, err error) {
+	e := AuditEntry{
+		Time:     time.Now(),
+		Endpoint: urls[0],
+		Query:    strings.Join(urls[1:], "/"),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Cached = call.result.Cached
+		e.Stale = call.result.Stale
+		if resp != nil {
+			e.Result = auditSummary(e.Endpoint, &resp.Data)
+		}
+	}
+	a.write(e)
+}
+
+// logLocal writes an entry for a query answered with result, worked out
+// locally as the API could not be reached.
+func (a *auditLog) logLocal(endpoint, query, result string) {
+	a.write(AuditEntry{
+		Time:     time.Now(),
+		Endpoint: endpoint,
+		Query:    query,
+		Result:   result,
+		Local:    true,
+	})
+}
+
+func (a *auditLog) write(e AuditEntry) {
+	if a.w == nil {
+		return
+	}
+	for _, redact := range a.redact {
+		if !redact(&e) {
+			return
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.w.Write(append(line, '\n'))
+}
+
+// auditSummary returns a short description of the answer d from endpoint.
+func auditSummary(endpoint string, d *data) string {
+	switch endpoint {
+	case "route":
+		return d.Route
+	case "origin":
+		return ASN(d.Origin).String()
+	case "aspath":
+		return strings.Join(append(append([]string(nil), d.ASPath...), d.ASSet...), " ")
+	case "roa":
+		return fmt.Sprintf("%s %s %s", d.Route, ASN(d.Origin), d.ROA)
+	case "asname":
+		return d.ASName
+	case "asnames":
+		return fmt.Sprintf("%d names", len(d.ASNames))
+	case "sourced":
+		return fmt.Sprintf("%d prefixes", len(d.Sourced.Prefixes))
+	case "totals":
+		return fmt.Sprintf("%d IPv4 %d IPv6", d.Totals.Ipv4, d.Totals.Ipv6)
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package bgpstuff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	c := newTestClient(t, fakeAPI,
+		WithAuditLog(&buf),
+		WithAuditRedact(RedactQuery),
+		WithAuditRedact(func(e *AuditEntry) bool { return e.Endpoint != "aspath" }),
+	)
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.GetASPath("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetASName(13335); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []AuditEntry
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e AuditEntry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		e.Time = time.Time{}
+		got = append(got, e)
+	}
+	want := []AuditEntry{
+		{Endpoint: "origin", Query: "1.1.1.0/24", Result: "AS13335"},
+		{Endpoint: "asname", Query: "13335", Result: "CLOUDFLARENET"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %+v, Want: %+v", got, want)
+	}
+}
+
+func TestAuditLogFailures(t *testing.T) {
+	t.Parallel()
+	var down int32
+	var buf bytes.Buffer
+	c := newTestClient(t, downAPI(&down, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sourced/13335" {
+			fmt.Fprint(w, `{"Response":{"Sourced":{"Ipv4":1,"Ipv6":0,"Prefixes":["1.1.1.0/24"]}}}`)
+			return
+		}
+		fakeAPI(w, r)
+	}), WithAuditLog(&buf), WithAuditRedact(RedactQuery), WithLocalOriginFallback())
+	if _, _, _, err := c.GetSourced(13335); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&down, apiDown)
+	buf.Reset()
+
+	// The fallback's answer is logged as an answer, not as the failure.
+	if origin, err := c.GetOrigin("1.1.1.1"); err != nil || origin != 13335 {
+		t.Fatalf("Got: %d, %v, Want: 13335", origin, err)
+	}
+	var e AuditEntry
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	e.Time = time.Time{}
+	if want := (AuditEntry{Endpoint: "origin", Query: "1.1.1.0/24", Result: "AS13335", Local: true}); e != want {
+		t.Errorf("Got: %+v, Want: %+v", e, want)
+	}
+
+	// A transport error carries the request URL, which must not give away
+	// the host either.
+	buf.Reset()
+	c.api = "http://127.0.0.1:1"
+	if _, err := c.GetRoute("1.1.1.1"); err == nil {
+		t.Fatal("Expected error, but no error returned")
+	}
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Error == "" || strings.Contains(e.Error, "1.1.1.1") {
+		t.Errorf("Got error: %q, Want it without 1.1.1.1", e.Error)
+	}
+}
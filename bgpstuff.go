@@ -1,315 +1,995 @@
 package bgpstuff
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mellowdrifter/bogons"
-	"golang.org/x/time/rate"
 )
 
+const version = "1.0.0"
+
+// API base URLs.
 const (
-	version = "1.0.0"
-	liveapi = "https://bgpstuff.net"
-	testapi = "https://test.bgpstuff.net"
+	// LiveAPI is bgpstuff.net, used unless the client is created for
+	// testing.
+	LiveAPI = "https://bgpstuff.net"
+	// TestAPI is the test instance of bgpstuff.net.
+	TestAPI = "https://test.bgpstuff.net"
 )
 
+// EnvAPI is the environment variable that, when set, gives the base URL of
+// the API in place of LiveAPI or TestAPI. WithBaseURL still takes priority,
+// so a deployment can be pointed at another server without code changes.
+const EnvAPI = "BGPSTUFF_API"
+
 var (
-	errInvalidIP  = errors.New("invalid IP")
-	errInvalidASN = errors.New("invalid AS Number")
-	rpm           = 30 // requests per minute
+	// ErrInvalidIP is returned when an argument is not a valid public IP.
+	ErrInvalidIP = errors.New("invalid IP")
+	// ErrInvalidASN is returned when an argument is not a valid public ASN.
+	ErrInvalidASN = errors.New("invalid AS Number")
+	// ErrResponseTooLarge is returned when a response body is larger than
+	// the limit set with WithMaxResponseBytes.
+	ErrResponseTooLarge = errors.New("response body too large")
+	// ErrUnexpectedResponse is returned when the server answers with a
+	// different action than the one asked for, such as a route when an
+	// origin was requested.
+	ErrUnexpectedResponse = errors.New("unexpected response")
+	// ErrNonJSONResponse is returned when the answer is not JSON, such as
+	// an HTML error page from a proxy or CDN in front of the API. The
+	// error includes the start of the body.
+	ErrNonJSONResponse = errors.New("response is not JSON")
+	rpm                = 30 // requests per minute
 )
 
 // Client is a client to the bgpstuff.net REST API
 type Client struct {
-	Loc      string
-	limiter  *rate.Limiter
-	api      string
-	ASNames  map[int]string
+	Loc     string
+	limiter Limiter
+	api     string
+	// ASNames is filled by GetASNames. It is replaced, not updated, by
+	// each call, and changing it does not change what GetASName returns.
+	//
+	// Deprecated: use ASNameTable or GetASInfo instead.
+	ASNames map[int]string
+	// Invalids is filled by GetInvalids, unless the client was created
+	// WithInvalidCountsOnly. It is replaced, not updated, by each call, and
+	// changing it does not change what GetInvalid returns.
+	//
+	// Deprecated: use InvalidTable instead.
 	Invalids map[int][]*net.IPNet
+
+	asinfo             map[uint32]ASNumName
+	asinfoFetched      time.Time
+	invalids           map[int][]*net.IPNet
+	invalidsByPrefix   map[netip.Prefix][]ASN
+	invalidCounts      map[int]int
+	invalidPrefixes    map[int][]*net.IPNet
+	invalidCountsOnly  bool
+	sourced            map[int]int
+	maxResponseBytes   int64
+	pollInterval       time.Duration
+	notifiers          []Notifier
+	notifyErr          func(Notifier, Event, error)
+	staleIfError       time.Duration
+	cacheTTL           time.Duration
+	negativeTTL        time.Duration
+	cacheSize          int
+	cache              *responseCache
+	coalesce           *coalescer
+	requestHooks       []func(*http.Request)
+	responseHooks      []func(*http.Response, time.Duration)
+	resolver           *net.Resolver
+	apiAddr            string
+	network            string
+	proxy              *url.URL
+	maxIdlePerHost     int
+	idleTimeout        time.Duration
+	tlsConfig          *tls.Config
+	insecureSkipVerify bool
+	httpClient         *http.Client
+	metrics            *metrics
+	allowPrivate       bool
+	slurm              *SLURM
+	origins            *originIndex
+	aliases            map[string]string
+	latency            *latencyTracker
+	separateLookups    bool
+	storeDir           string
+	store              *store
+	storeHistoryLimit  int64
+	audit              *auditLog
+	progress           func(done, total int64)
+	// tablesMu guards swapping the tables loaded by GetASNames and
+	// GetInvalids, the VRPs and SLURM file, and the sourced counts. The tables themselves
+	// are never changed once swapped in.
+	tablesMu sync.RWMutex
+	// loadedVRPs holds the VRPs set with SetVRPs, and vrps those CheckROA
+	// validates against once any SLURM file is applied, keyed by prefix.
+	loadedVRPs []VRP
+	vrps       map[netip.Prefix][]VRP
+	// sched orders requests waiting for the limiter by priority.
+	sched scheduler
+	// sem holds a token for each request in flight when the number of
+	// concurrent requests is limited.
+	sem chan struct{}
+	// ensureMu stops EnsureASNames and EnsureInvalids loading a table
+	// more than once at a time.
+	ensureMu sync.Mutex
+	// life tracks requests and watchers so Close can wait for them.
+	life *lifecycle
 }
 
-// NewBGPClient return a pointer to a new client
+// NewBGPClient return a pointer to a new client. It uses TestAPI if testing
+// is set and LiveAPI otherwise, unless EnvAPI is set.
 // TODO: Hate setting testing here...
-func NewBGPClient(testing bool) *Client {
-	r := rate.Every(time.Minute / time.Duration(rpm))
-	limit := rate.NewLimiter(r, rpm)
-
-	var api string
+func NewBGPClient(testing bool, opts ...Option) *Client {
+	api := LiveAPI
 	if testing {
-		api = testapi
-	} else {
-		api = liveapi
+		api = TestAPI
 	}
-
-	return &Client{
-		limiter: limit,
-		api:     api,
+	if env := os.Getenv(EnvAPI); env != "" {
+		api = strings.TrimRight(env, "/")
 	}
-}
 
-func newHTTPClient(timeout time.Duration) *http.Client {
-	return &http.Client{
-		Timeout: timeout,
+	c := &Client{
+		limiter:   newRateLimiter(rpm),
+		api:       api,
+		cacheSize: defaultCacheSize,
+		metrics:   newMetrics(),
+		life:      newLifecycle(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	if c.storeDir != "" {
+		c.openStore()
+	}
+	c.cache = newResponseCache(c.cacheSize)
+	c.cache.evicted = c.metrics.eviction
+	c.httpClient = &http.Client{Transport: c.newTransport()}
+	return c
 }
 
+// getURI builds the URL for the endpoint and arguments in urls. They are
+// added to the path of the base URL, so an API mounted under a path such
+// as https://tools.example.com/bgpstuff/ works, and any query in the base
+// URL is kept. Each is escaped as a path segment.
 func (c *Client) getURI(urls []string) string {
-	var uri strings.Builder
-	uri.WriteString(c.api)
+	u, err := url.Parse(c.api)
+	if err != nil {
+		return c.api + "/" + strings.Join(urls, "/")
+	}
+	escaped := strings.TrimRight(u.EscapedPath(), "/")
 	for _, v := range urls {
-		uri.WriteString("/")
-		uri.WriteString(v)
+		escaped += "/" + url.PathEscape(v)
 	}
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return c.api + "/" + strings.Join(urls, "/")
+	}
+	u.Path, u.RawPath = path, escaped
+	return u.String()
+}
 
-	return uri.String()
+// normalizeIP returns the canonical text form of ip, so that equivalent
+// inputs such as "::FFFF:1.1.1.1" and "1.1.1.1" build the same URL. Brackets,
+// surrounding whitespace and any IPv6 zone are removed first.
+func normalizeIP(ip string) (string, bool) {
+	ip = strings.TrimSpace(ip)
+	ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+	if i := strings.IndexByte(ip, '%'); i >= 0 {
+		ip = ip[:i]
+	}
+	p := net.ParseIP(ip)
+	if p == nil {
+		return "", false
+	}
+	return p.String(), true
 }
 
-// getRequest will take a handler and any arugments and request
-// a response from the bgpstuff.net API. Timeouts are set to 5 seconds
-// to prevent hanging connections.
-func (c Client) getRequest(urls ...string) (*response, error) {
-	if err := c.limiter.Wait(context.Background()); err != nil {
-		return nil, err
+// normalizeArg is normalizeIP for lookup arguments, which may also be a
+// prefix, in which case its network address is used.
+func normalizeArg(ip string) (string, bool) {
+	if p, ok := normalizeIP(ip); ok {
+		return p, true
+	}
+	_, prefix, err := net.ParseCIDR(strings.TrimSpace(ip))
+	if err != nil {
+		return "", false
+	}
+	return prefix.IP.String(), true
+}
+
+// validIP returns the normalized form of ip, and whether the client may
+// look it up. Only public addresses may be, unless WithAllowPrivate is set.
+// A prefix may be given in place of an IP, in which case its network
+// address is used.
+func (c *Client) validIP(ip string) (string, bool) {
+	p, ok := normalizeArg(ip)
+	if !ok {
+		return "", false
 	}
-	client := newHTTPClient(time.Second * 8)
+	return p, c.allowPrivate || bogons.ValidPublicIP(p)
+}
 
+// validASN reports whether the client may look up asn. Only public ASNs may
+// be, unless WithAllowPrivate is set.
+func (c *Client) validASN(asn int) bool {
+	if asn < 0 || int64(asn) > math.MaxUint32 {
+		return false
+	}
+	return c.allowPrivate || bogons.ValidPublicASN(uint32(asn))
+}
+
+// lookupError wraps err with the endpoint and argument that caused it.
+// The original error is kept so it can still be matched with errors.Is.
+func lookupError(endpoint, arg string, err error) error {
+	if arg == "" {
+		return fmt.Errorf("%s lookup: %w", endpoint, err)
+	}
+	return fmt.Errorf("%s lookup for %q: %w", endpoint, arg, err)
+}
+
+// getRequest will take a handler and any arugments and request
+// a response from the bgpstuff.net API. Answers still within their cache
+// TTL are returned without a request. If the API can't be reached and the
+// client was created WithStaleIfError, a recent enough cached or stored
+// answer is returned in its place and flagged as stale in the call's Meta. Each query is
+// written to the audit log, if the client keeps one.
+func (c *Client) getRequest(call *callOptions, urls ...string) (*response, error) {
+	resp, err := c.getResponse(call, urls...)
+	if c.audit != nil {
+		c.audit.log(call, urls, resp, err)
+	}
+	return resp, err
+}
+
+// getResponse answers the query made up of urls from the cache, the API,
+// or failing that a stale answer.
+func (c *Client) getResponse(call *callOptions, urls ...string) (*response, error) {
 	uri := c.getURI(urls)
 
-	re, err := http.NewRequest("GET", uri, nil)
+	endpoint := urls[0]
+
+	if !call.noCache {
+		if entry, ok := c.cache.fresh(uri, c.cacheTTL, c.negativeTTL); ok {
+			c.metrics.cacheHit(endpoint, false)
+			call.setMeta(responseMeta(entry.resp, Meta{Cached: true, FetchedAt: entry.fetched, Source: SourceCache}))
+			return entry.resp, nil
+		}
+	}
+
+	fetch := func() (*response, error) {
+		resp, err := c.doRequest(call, endpoint, uri)
+		if err == nil {
+			err = checkAction(endpoint, resp.Data.Action)
+		}
+		c.metrics.request(endpoint, call.requestTime(), err)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	var resp *response
+	var err error
+	if c.coalesce != nil && !call.noCache {
+		resp, err = c.coalesce.do(call.ctx, uri, fetch)
+	} else {
+		resp, err = fetch()
+	}
 	if err != nil {
+		if c.staleIfError > 0 && call.ctx.Err() == nil && !call.noCache && unreachable(err) {
+			if entry, ok := c.cache.get(uri, c.staleIfError); ok {
+				c.metrics.cacheHit(endpoint, true)
+				call.setMeta(responseMeta(entry.resp, Meta{Stale: true, Cached: true, FetchedAt: entry.fetched, Source: SourceStale}))
+				return entry.resp, nil
+			}
+			if c.store != nil {
+				if o, ok := c.store.last(urls); ok && time.Since(o.Time) <= c.staleIfError {
+					call.setMeta(responseMeta(&response{Data: o.Data}, Meta{Stale: true, FetchedAt: o.Time, Source: SourceStale}))
+					return &response{Data: o.Data}, nil
+				}
+			}
+		}
+		return nil, err
+	}
+
+	entry := cacheEntry{
+		endpoint: endpoint,
+		resp:     resp,
+		fetched:  time.Now(),
+		negative: isNegative(endpoint, &resp.Data),
+	}
+	if c.staleIfError > 0 || (entry.negative && c.negativeTTL > 0) || (!entry.negative && c.cacheTTL > 0) {
+		c.cache.put(uri, entry)
+	}
+	if c.store != nil {
+		c.store.record(urls, &resp.Data, entry.fetched)
+	}
+	call.setMeta(responseMeta(resp, Meta{FetchedAt: entry.fetched}))
+
+	return resp, nil
+}
+
+// checkAction returns ErrUnexpectedResponse if the server says it answered
+// something other than endpoint, so a misrouted answer is never taken for
+// the one asked for or cached in its place. Responses that don't name their
+// action aren't checked.
+func checkAction(endpoint, action string) error {
+	if action != "" && action != endpoint {
+		return fmt.Errorf("%w: got %q, want %q", ErrUnexpectedResponse, action, endpoint)
+	}
+	return nil
+}
+
+// statusError is returned when the API answers with a status other than
+// 200 OK.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received status: %s (%d)", http.StatusText(e.code), e.code)
+}
+
+// unreachable reports whether err means the API could not be reached or
+// could not answer: a transport error, a timeout, or a 5xx status. Other
+// errors, such as a 4xx status or an answer that can't be decoded, mean
+// the API is up and answered, so they are never papered over with an
+// answer fetched earlier. Nor is a request cancelled by its caller or by
+// Shutdown.
+func unreachable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrClientClosed) {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// doRequest fetches and decodes uri.
+func (c *Client) doRequest(call *callOptions, endpoint, uri string) (*response, error) {
+	var resp response
+	if err := c.fetch(call, endpoint, uri, resp.decodeJSON); err != nil {
 		return nil, err
 	}
+	resp.timing = call.timing
+
+	return &resp, nil
+}
+
+// fetch waits for the rate limiter, requests uri and hands the body of a
+// successful response to decode. Timeouts are set to 8 seconds to prevent
+// hanging connections, unless the call sets its own or the client was
+// created WithAdaptiveTimeout.
+func (c *Client) fetch(call *callOptions, endpoint, uri string, decode func(io.Reader) error) error {
+	call.sent = time.Time{}
+	ctx, end, err := c.life.begin(call.ctx, false)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if err := c.wait(ctx, call.priority); err != nil {
+		return err
+	}
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call.sent = time.Now()
+	timeout := defaultTimeout
+	if call.timeout > 0 {
+		timeout = call.timeout
+	} else if c.latency != nil {
+		timeout = c.latency.timeout(endpoint)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	re, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return err
+	}
 	re.Header.Set("Content-Type", "application/json")
 	re.Header.Set("User-Agent", fmt.Sprintf("go-bgpstuff.net/%s", version))
+	for _, hook := range c.requestHooks {
+		hook(re)
+	}
 
-	res, err := client.Do(re)
+	start := time.Now()
+	res, err := c.httpClient.Do(re)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer res.Body.Close()
+	call.timing = timing{
+		rtt:    time.Since(start),
+		server: parseServerTiming(res.Header.Values("Server-Timing")),
+	}
+	for _, hook := range c.responseHooks {
+		hook(res, time.Since(start))
+	}
+
+	br := bufio.NewReader(res.Body)
+	if err := checkJSON(res, br); err != nil {
+		return err
 	}
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received status: %s (%d)", http.StatusText(res.StatusCode), res.StatusCode)
+		return &statusError{code: res.StatusCode}
 	}
 
-	defer res.Body.Close()
+	var body io.Reader = br
+	if c.progress != nil && progressEndpoints[endpoint] {
+		body = &progressReader{r: body, total: res.ContentLength, fn: c.progress}
+	}
+	if c.maxResponseBytes > 0 {
+		body = &limitedReader{r: body, n: c.maxResponseBytes}
+	}
+	if c.aliases != nil {
+		decode = c.aliasDecoder(decode)
+	}
 
-	var resp response
-	if err := resp.decodeJSON(res.Body); err != nil {
-		return &resp, err
+	if err := decode(body); err != nil {
+		return err
 	}
+	if c.latency != nil {
+		c.latency.record(endpoint, time.Since(start))
+	}
+	return nil
+}
 
-	return &resp, nil
+// GetRoute uses the /route handler. ip may also be a prefix, in which case
+// its network address is looked up. It returns nil if there is no route
+// for ip, or if ip is only covered by a default route; use GetRouteResult
+// to tell the two apart.
+func (c *Client) GetRoute(ip string, opts ...CallOption) (*net.IPNet, error) {
+	res, err := c.GetRouteResult(ip, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Exists || res.Default {
+		return nil, nil
+	}
+
+	_, ipnet, err := net.ParseCIDR(res.Prefix.String())
+	if err != nil {
+		return nil, lookupError("route", ip, err)
+	}
+
+	return ipnet, nil
 }
 
-// GetRoute uses the /route handler
-func (c *Client) GetRoute(ip string) (*net.IPNet, error) {
-	if !bogons.ValidPublicIP(ip) {
-		return nil, errInvalidIP
+// GetRouteResult uses the /route handler and reports whether ip has a
+// route, and if so whether it is only a default route. The error is only
+// non-nil if the lookup itself failed.
+func (c *Client) GetRouteResult(ip string, opts ...CallOption) (*RouteResult, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return nil, lookupError("route", ip, ErrInvalidIP)
 	}
 
-	p := net.ParseIP(ip)
-	resp, err := c.getRequest("route", p.String())
+	resp, err := c.getRequest(newCallOptions(opts), "route", p)
 	if err != nil {
-		return nil, err
+		return nil, lookupError("route", ip, err)
 	}
 
-	// Response could be no route.
-	if resp.Data.Route == "" {
-		return nil, nil
+	res, err := parseRoute(resp.Data.Route, p)
+	if err != nil {
+		return nil, lookupError("route", ip, err)
 	}
 
-	// TODO: stop returning "/0"
-	if resp.Data.Route == "/0" {
-		return nil, err
+	return res, nil
+}
+
+// parseRoute turns the route field of a response for ip into a RouteResult.
+// The server sends an empty route when there is none, and "/0" when only a
+// default route matches.
+func parseRoute(route, ip string) (*RouteResult, error) {
+	switch route {
+	case "":
+		return &RouteResult{}, nil
+	case "/0":
+		def := netip.MustParsePrefix("0.0.0.0/0")
+		if addr, err := netip.ParseAddr(ip); err == nil && addr.Is6() {
+			def = netip.MustParsePrefix("::/0")
+		}
+		return &RouteResult{Prefix: def, Exists: true, Default: true}, nil
 	}
 
-	_, ipnet, err := net.ParseCIDR(resp.Data.Route)
+	prefix, err := netip.ParsePrefix(route)
 	if err != nil {
 		return nil, err
 	}
 
-	return ipnet, nil
+	return &RouteResult{Prefix: prefix, Exists: true, Default: prefix.Bits() == 0}, nil
 }
 
-// GetOrigin uses the /origin handler.
-func (c *Client) GetOrigin(ip string) (int, error) {
-	if !bogons.ValidPublicIP(ip) {
-		return 0, errInvalidIP
+// GetOrigin uses the /origin handler. ip may also be a prefix, in which
+// case its network address is looked up.
+func (c *Client) GetOrigin(ip string, opts ...CallOption) (int, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return 0, lookupError("origin", ip, ErrInvalidIP)
 	}
 
-	p := net.ParseIP(ip)
-	resp, err := c.getRequest("origin", p.String())
+	// The audit entry is written here rather than by getRequest, so an
+	// answer from the local fallback isn't logged as a failure.
+	call := newCallOptions(opts)
+	resp, err := c.getResponse(call, "origin", p)
+	if err != nil {
+		if asn, ok := c.localOrigin(call, p, err); ok {
+			if c.audit != nil {
+				c.audit.logLocal("origin", p, ASN(asn).String())
+			}
+			return asn, nil
+		}
+	}
+	if c.audit != nil {
+		c.audit.log(call, []string{"origin", p}, resp, err)
+	}
 	if err != nil {
-		return 0, err
+		return 0, lookupError("origin", ip, err)
 	}
 
 	return resp.Data.Origin, nil
 }
 
-func getASPathFromResponse(res *response) ([]int, []int) {
+// getASPathFromResponse parses the path and set in res. An AS number that
+// doesn't parse is an error rather than a 0 in the path, so a corrupted
+// response is never passed off as a real path.
+func getASPathFromResponse(res *response) ([]int, []int, error) {
 	if len(res.Data.ASPath) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
-	path := make([]int, 0, len(res.Data.ASPath))
-	for _, v := range res.Data.ASPath {
-		i, _ := strconv.Atoi(v)
-		path = append(path, i)
+	path, err := parseASNs(res.Data.ASPath)
+	if err != nil {
+		return nil, nil, err
 	}
 	if len(res.Data.ASSet) > 0 {
-		as := make([]int, 0, len(res.Data.ASSet))
-		for _, v := range res.Data.ASSet {
-			i, _ := strconv.Atoi(v)
-			as = append(as, i)
+		set, err := parseASNs(res.Data.ASSet)
+		if err != nil {
+			return nil, nil, err
 		}
-		return path, as
+		return path, set, nil
 	}
 
-	return path, nil
+	return path, nil, nil
+}
+
+func parseASNs(asns []string) ([]int, error) {
+	out := make([]int, 0, len(asns))
+	for _, v := range asns {
+		i, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad AS number %q in path", v)
+		}
+		out = append(out, int(i))
+	}
+	return out, nil
 }
 
 // GetASPath uses the /aspath handler.
-func (c *Client) GetASPath(ip string) ([]int, []int, error) {
-	if !bogons.ValidPublicIP(ip) {
-		return nil, nil, errInvalidIP
+func (c *Client) GetASPath(ip string, opts ...CallOption) ([]int, []int, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return nil, nil, lookupError("aspath", ip, ErrInvalidIP)
 	}
 
-	p := net.ParseIP(ip)
-	resp, err := c.getRequest("aspath", p.String())
+	resp, err := c.getRequest(newCallOptions(opts), "aspath", p)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, lookupError("aspath", ip, err)
 	}
 
-	paths, sets := getASPathFromResponse(resp)
+	paths, sets, err := getASPathFromResponse(resp)
+	if err != nil {
+		return nil, nil, lookupError("aspath", ip, err)
+	}
 	return paths, sets, nil
 }
 
-// GetROA uses the /roa handler.
-func (c *Client) GetROA(ip string) (string, error) {
-	if !bogons.ValidPublicIP(ip) {
-		return "", errInvalidIP
+// GetASPathDetail uses the /aspath handler and returns the path with any
+// AS set as its own trailing segment. The API does not say where in the path
+// the set belongs. It returns nil if there is no path for ip.
+func (c *Client) GetASPathDetail(ip string, opts ...CallOption) (*ASPath, error) {
+	path, set, err := c.GetASPath(ip, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 0 && len(set) == 0 {
+		return nil, nil
 	}
+	return newASPath(path, set), nil
+}
 
-	p := net.ParseIP(ip)
-	resp, err := c.getRequest("roa", p.String())
+// GetROA uses the /roa handler. ip may also be a prefix, in which case its
+// network address is looked up.
+func (c *Client) GetROA(ip string, opts ...CallOption) (string, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return "", lookupError("roa", ip, ErrInvalidIP)
+	}
+
+	resp, err := c.getRequest(newCallOptions(opts), "roa", p)
 	if err != nil {
-		return "", err
+		return "", lookupError("roa", ip, err)
 	}
 
+	return c.roaStatus(resp), nil
+}
+
+// roaStatus returns the ROA state in a /roa response, with any SLURM
+// exceptions applied.
+func (c *Client) roaStatus(resp *response) string {
 	// If there is no origin, there is no prefix ROA to check.
 	if resp.Data.Origin == 0 {
-		return "", nil
+		return ""
 	}
 
-	return resp.Data.ROA, nil
+	if slurm := c.slurmRules(); slurm != nil {
+		if prefix, err := netip.ParsePrefix(resp.Data.Route); err == nil {
+			return string(slurm.Status(ROAStatus(resp.Data.ROA), prefix, ASN(resp.Data.Origin)))
+		}
+	}
+
+	return resp.Data.ROA
 }
 
 // GetASName uses the /asname handler
-func (c *Client) GetASName(asn int) (string, error) {
-	if !bogons.ValidPublicASN(uint32(asn)) {
-		return "", errInvalidASN
+func (c *Client) GetASName(asn int, opts ...CallOption) (string, error) {
+	if !c.validASN(asn) {
+		return "", lookupError("asname", fmt.Sprint(asn), ErrInvalidASN)
 	}
 
 	// Check asnames if it has the entry
-	if len(c.ASNames) > 1 {
-		if name, ok := c.ASNames[asn]; ok {
-			return name, nil
+	call := newCallOptions(opts)
+	if asinfo := c.asTable(); len(asinfo) > 1 {
+		call.setMeta(Meta{FetchedAt: c.asTableFetched(), Source: SourceLocalTable})
+		if info, ok := asinfo[uint32(asn)]; ok {
+			return info.ASName, nil
 		}
 		return "", nil
 	}
 
-	resp, err := c.getRequest("asname", fmt.Sprint(asn))
+	resp, err := c.getRequest(call, "asname", fmt.Sprint(asn))
 	if err != nil {
-		return "", err
+		return "", lookupError("asname", fmt.Sprint(asn), err)
 	}
 
 	return resp.Data.ASName, nil
 }
 
-// GetASNames uses the /asnames handler
-func (c *Client) GetASNames() error {
-	c.ASNames = make(map[int]string)
+// RouteExists uses the /route handler and reports whether the server has a
+// route for ip. A default route counts, as it does in GetRouteResult. It is
+// for callers that only need a yes or no.
+func (c *Client) RouteExists(ip string, opts ...CallOption) (bool, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return false, lookupError("route", ip, ErrInvalidIP)
+	}
 
-	resp, err := c.getRequest("asnames")
+	resp, err := c.getRequest(newCallOptions(opts), "route", p)
 	if err != nil {
-		return err
+		return false, lookupError("route", ip, err)
+	}
+
+	return resp.Data.Exists && resp.Data.Route != "", nil
+}
+
+// ASNExists uses the /asname handler and reports whether the server knows
+// a name for asn. If the AS names have been loaded with GetASNames they are
+// checked instead, without a request.
+func (c *Client) ASNExists(asn int, opts ...CallOption) (bool, error) {
+	if !c.validASN(asn) {
+		return false, lookupError("asname", fmt.Sprint(asn), ErrInvalidASN)
+	}
+
+	call := newCallOptions(opts)
+	if asinfo := c.asTable(); len(asinfo) > 1 {
+		call.setMeta(Meta{FetchedAt: c.asTableFetched(), Source: SourceLocalTable})
+		_, ok := asinfo[uint32(asn)]
+		return ok, nil
+	}
+
+	resp, err := c.getRequest(call, "asname", fmt.Sprint(asn))
+	if err != nil {
+		return false, lookupError("asname", fmt.Sprint(asn), err)
 	}
 
+	return resp.Data.Exists && resp.Data.ASName != "", nil
+}
+
+// GetASNames uses the /asnames handler
+func (c *Client) GetASNames(opts ...CallOption) error {
+	call := bulkCallOptions(opts)
+	resp, err := c.getRequest(call, "asnames")
+	if err != nil {
+		return lookupError("asnames", "", err)
+	}
+
+	asinfo := make(map[uint32]ASNumName, len(resp.Data.ASNames))
+	names := make(map[int]string, len(resp.Data.ASNames))
 	for _, v := range resp.Data.ASNames {
-		c.ASNames[int(v.ASN)] = v.ASName
+		asinfo[v.ASN] = v
+		names[int(v.ASN)] = v.ASName
+	}
+	c.setASTable(asinfo, names, call.result.FetchedAt)
+	if c.store != nil && !call.result.Stale {
+		c.store.saveASNames(resp.Data.ASNames, call.result.FetchedAt)
 	}
 
 	return nil
 }
 
-// GetInvalids grabs all current invalids and populates c.Invalids
-func (c *Client) GetInvalids() error {
-	c.Invalids = make(map[int][]*net.IPNet)
+// GetASNamesFor returns the names of only the given ASNs, keyed by ASN.
+// Names come from the table loaded by GetASNames if there is one, otherwise
+// each ASN is looked up with the /asname handler. ASNs without a name are
+// left out of the result.
+func (c *Client) GetASNamesFor(asns []int, opts ...CallOption) (map[int]string, error) {
+	names := make(map[int]string, len(asns))
+	for _, asn := range asns {
+		if _, ok := names[asn]; ok {
+			continue
+		}
+		name, err := c.GetASName(asn, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			names[asn] = name
+		}
+	}
+
+	return names, nil
+}
+
+// GetInvalids grabs all current invalids and populates the invalids table
+func (c *Client) GetInvalids(opts ...CallOption) error {
+	if c.invalidCountsOnly {
+		counts, err := c.fetchInvalidCounts(bulkCallOptions(opts))
+		if err != nil {
+			return err
+		}
+		c.setInvalidCounts(counts)
+		return nil
+	}
+	invalids, err := c.fetchInvalids(bulkCallOptions(opts), nil)
+	if err != nil {
+		return err
+	}
+	c.setInvalidTable(invalids)
+	if c.store != nil {
+		c.store.saveInvalids(invalids)
+	}
+	return nil
+}
 
-	resp, err := c.getRequest("invalids")
+// GetInvalidsFor is like GetInvalids, but only keeps the invalids of the
+// given ASNs in the table. The rest are skipped as the response is read, so
+// the full table is never held in memory. If the client was created
+// WithInvalidCountsOnly, the counts are left as they are and the prefixes
+// are kept for GetInvalid to answer from.
+func (c *Client) GetInvalidsFor(asns []int, opts ...CallOption) error {
+	want := make(map[int]bool, len(asns))
+	for _, asn := range asns {
+		want[asn] = true
+	}
+	invalids, err := c.fetchInvalids(bulkCallOptions(opts), func(asn int) bool { return want[asn] })
 	if err != nil {
 		return err
 	}
+	if c.invalidCountsOnly {
+		c.addFetchedInvalids(asns, invalids)
+		return nil
+	}
+	c.setInvalidTable(invalids)
+	return nil
+}
+
+// fetchInvalids downloads and parses the invalids table without touching
+// the client's copy. The response is decoded as it streams in, and only
+// ASNs for which keep returns true are parsed. A nil keep keeps them all.
+// Being streamed, the table is not held in the response cache.
+func (c *Client) fetchInvalids(call *callOptions, keep func(asn int) bool) (map[int][]*net.IPNet, error) {
+	var invalids map[int][]*net.IPNet
+	err := c.streamInvalids(call, func(r io.Reader) (int, error) {
+		var err error
+		invalids, err = decodeInvalids(r, keep)
+		return len(invalids), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if slurm := c.slurmRules(); slurm != nil {
+		slurm.filterInvalids(invalids)
+	}
+	return invalids, nil
+}
 
-	for _, v := range resp.Data.Invalids {
-		prefixes := make([]*net.IPNet, 0, len(v.Prefixes))
-		for _, prefix := range v.Prefixes {
-			_, ipnet, err := net.ParseCIDR(prefix)
+// fetchInvalidCounts is fetchInvalids for WithInvalidCountsOnly. Prefixes
+// are only parsed if a SLURM file has been applied, to see which are still
+// invalid.
+func (c *Client) fetchInvalidCounts(call *callOptions) (map[int]int, error) {
+	slurm := c.slurmRules()
+	count := func(asn int, prefixes []string) (int, error) {
+		if slurm == nil {
+			return len(prefixes), nil
+		}
+		n := 0
+		for _, s := range prefixes {
+			p, err := netip.ParsePrefix(s)
 			if err != nil {
-				return err
+				return 0, err
+			}
+			if slurm.Status(ROAInvalid, p.Masked(), ASN(asn)) == ROAInvalid {
+				n++
 			}
-			prefixes = append(prefixes, ipnet)
 		}
-		c.Invalids[int(v.ASN)] = prefixes
+		return n, nil
+	}
+	var counts map[int]int
+	err := c.streamInvalids(call, func(r io.Reader) (int, error) {
+		var err error
+		counts, err = decodeInvalidCounts(r, count)
+		return len(counts), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// streamInvalids requests the invalids table and hands the body to decode,
+// which returns how many ASNs it found.
+func (c *Client) streamInvalids(call *callOptions, decode func(io.Reader) (int, error)) error {
+	var asns int
+	err := c.fetch(call, "invalids", c.getURI([]string{"invalids"}), func(r io.Reader) error {
+		var err error
+		asns, err = decode(r)
+		return err
+	})
+	c.metrics.request("invalids", call.requestTime(), err)
+	if c.audit != nil {
+		e := AuditEntry{Time: time.Now(), Endpoint: "invalids"}
+		if err != nil {
+			e.Error = err.Error()
+		} else {
+			e.Result = fmt.Sprintf("%d ASNs", asns)
+		}
+		c.audit.write(e)
 	}
+	if err != nil {
+		return lookupError("invalids", "", err)
+	}
+	call.setMeta(Meta{
+		FetchedAt:    time.Now(),
+		Source:       SourceAPI,
+		Action:       "invalids",
+		RTT:          call.timing.rtt,
+		ServerTiming: call.timing.server,
+	})
 	return nil
 }
 
-// GetInvalid implements the /invalid handler
+// GetInvalid implements the /invalid handler. It returns a copy of the
+// prefixes from the table loaded by GetInvalids, which the caller may
+// modify. It returns ErrCacheCold if the table has not been loaded.
+//
+// If the client was created WithInvalidCountsOnly, the prefixes of an ASN
+// with invalids are downloaded on demand instead, and kept until the
+// counts are next loaded. Each download is of the whole table, so to look
+// up many ASNs load them all at once with GetInvalidsFor first.
 func (c *Client) GetInvalid(asn int) ([]*net.IPNet, error) {
-	if !bogons.ValidPublicASN(uint32(asn)) {
-		return nil, errInvalidASN
+	if !c.validASN(asn) {
+		return nil, lookupError("invalid", fmt.Sprint(asn), ErrInvalidASN)
+	}
+
+	if c.invalidCountsOnly {
+		count, err := c.InvalidCount(asn)
+		if err != nil || count == 0 {
+			return nil, err
+		}
+		if prefixes, ok := c.fetchedInvalid(asn); ok {
+			return cloneIPNets(prefixes), nil
+		}
+		if err := c.GetInvalidsFor([]int{asn}); err != nil {
+			return nil, err
+		}
+		prefixes, _ := c.fetchedInvalid(asn)
+		return cloneIPNets(prefixes), nil
 	}
 
-	if c.Invalids == nil {
-		return nil, fmt.Errorf("invalids is empty, run GetInvalids() first")
+	invalids := c.invalidTable()
+	if invalids == nil {
+		return nil, lookupError("invalid", fmt.Sprint(asn), ErrCacheCold{Cache: "invalids"})
 	}
 
-	return c.Invalids[asn], nil
+	return cloneIPNets(invalids[asn]), nil
 }
 
 // GetSourced implements the /sourced handler
-func (c *Client) GetSourced(asn int) ([]*net.IPNet, int, int, error) {
-	if !bogons.ValidPublicASN(uint32(asn)) {
-		return nil, 0, 0, errInvalidASN
+func (c *Client) GetSourced(asn int, opts ...CallOption) ([]*net.IPNet, int, int, error) {
+	if !c.validASN(asn) {
+		return nil, 0, 0, lookupError("sourced", fmt.Sprint(asn), ErrInvalidASN)
 	}
 
-	resp, err := c.getRequest("sourced", fmt.Sprint(asn))
+	call := newCallOptions(opts)
+	resp, err := c.getRequest(call, "sourced", fmt.Sprint(asn))
 	if err != nil {
-		return nil, 0, 0, err
+		return nil, 0, 0, lookupError("sourced", fmt.Sprint(asn), err)
 	}
 
 	prefixes := make([]*net.IPNet, 0, len(resp.Data.Sourced.Prefixes))
 	for _, v := range resp.Data.Sourced.Prefixes {
 		_, prefix, err := net.ParseCIDR(v)
 		if err != nil {
-			return nil, 0, 0, err
+			return nil, 0, 0, lookupError("sourced", fmt.Sprint(asn), err)
 		}
 		prefixes = append(prefixes, prefix)
 	}
+	if c.origins != nil && !call.result.Stale {
+		index := make([]netip.Prefix, 0, len(prefixes))
+		for _, prefix := range prefixes {
+			if p, ok := ipNetPrefix(prefix); ok {
+				index = append(index, p)
+			}
+		}
+		c.origins.update(asn, index, call.result.FetchedAt)
+	}
+	c.recordSourced(asn, resp.Data.Sourced.Ipv4+resp.Data.Sourced.Ipv6)
 	return prefixes, resp.Data.Sourced.Ipv4, resp.Data.Sourced.Ipv6, nil
 }
 
+// IsOriginatedBy reports whether asn originates prefix, or a less specific
+// prefix covering it, as GetSourced sees them. A single address can be
+// checked as a /32 or /128. Caching set up with WithCacheTTL applies, so
+// checking many prefixes against the same ASN makes one request.
+func (c *Client) IsOriginatedBy(prefix netip.Prefix, asn int, opts ...CallOption) (bool, error) {
+	if !prefix.IsValid() {
+		return false, lookupError("sourced", fmt.Sprint(asn), ErrInvalidIP)
+	}
+	nets, _, _, err := c.GetSourced(asn, opts...)
+	if err != nil {
+		return false, err
+	}
+	prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked()
+	for _, n := range nets {
+		p, ok := ipNetPrefix(n)
+		if ok && p.Bits() <= prefix.Bits() && p.Contains(prefix.Addr()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // GetTotals implements the /totals handler
-func (c *Client) GetTotals() (int, int, error) {
-	resp, err := c.getRequest("totals")
+func (c *Client) GetTotals(opts ...CallOption) (int, int, error) {
+	resp, err := c.getRequest(newCallOptions(opts), "totals")
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, lookupError("totals", "", err)
 	}
 
 	return resp.Data.Totals.Ipv4, resp.Data.Totals.Ipv6, nil
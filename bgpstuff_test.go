@@ -1,6 +1,7 @@
 package bgpstuff_test
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"testing"
@@ -368,3 +369,268 @@ func TestTotals(t *testing.T) {
 		t.Errorf("Expecting a certain amount of prefixes, but got %d IPv4 and %d IPv6", ipv4, ipv6)
 	}
 }
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ip   string
+		want bgpstuff.AddressClass
+	}{
+		{ip: "1.1.1.1", want: bgpstuff.ClassPublic},
+		{ip: "2600::", want: bgpstuff.ClassPublic},
+		{ip: "10.1.1.1", want: bgpstuff.ClassPrivate},
+		{ip: "172.20.0.1", want: bgpstuff.ClassPrivate},
+		{ip: "fd00::1", want: bgpstuff.ClassPrivate},
+		{ip: "100.64.1.1", want: bgpstuff.ClassCGNAT},
+		{ip: "169.254.1.1", want: bgpstuff.ClassLinkLocal},
+		{ip: "fe80::1", want: bgpstuff.ClassLinkLocal},
+		{ip: "224.0.0.5", want: bgpstuff.ClassMulticast},
+		{ip: "ff02::1", want: bgpstuff.ClassMulticast},
+		{ip: "192.0.2.1", want: bgpstuff.ClassDocumentation},
+		{ip: "2001:db8::1", want: bgpstuff.ClassDocumentation},
+		{ip: "127.0.0.1", want: bgpstuff.ClassLoopback},
+		{ip: "::1", want: bgpstuff.ClassLoopback},
+		{ip: "198.18.0.1", want: bgpstuff.ClassBenchmark},
+		{ip: "240.0.0.1", want: bgpstuff.ClassReserved},
+		{ip: "1.1.1.0/24", want: bgpstuff.ClassPublic},
+		{ip: "10.0.0.0/8", want: bgpstuff.ClassPrivate},
+		{ip: "1.1.1.1/33", want: bgpstuff.ClassInvalid},
+		{ip: "🥺", want: bgpstuff.ClassInvalid},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			if got := bgpstuff.Classify(tc.ip); got != tc.want {
+				t.Errorf("Got: %s, Want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	t.Parallel()
+	c := bgpstuff.NewBGPClient(true)
+	_, err := c.GetRoute("10.1.1.1")
+	if !errors.Is(err, bgpstuff.ErrInvalidIP) {
+		t.Errorf("Expected ErrInvalidIP, got: %v", err)
+	}
+	if want := `route lookup for "10.1.1.1": invalid IP`; err.Error() != want {
+		t.Errorf("Got: %s, Want: %s", err, want)
+	}
+	_, err = c.GetASName(0)
+	if !errors.Is(err, bgpstuff.ErrInvalidASN) {
+		t.Errorf("Expected ErrInvalidASN, got: %v", err)
+	}
+}
+
+func TestDetectPrepending(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		path      []int
+		wantASN   int
+		wantCount int
+	}{
+		{name: "empty"},
+		{name: "no prepends", path: []int{174, 3356, 13335}},
+		{name: "origin prepend", path: []int{174, 13335, 13335, 13335}, wantASN: 13335, wantCount: 3},
+		{name: "transit prepend", path: []int{174, 174, 3356, 13335}, wantASN: 174, wantCount: 2},
+		{name: "longest wins", path: []int{174, 174, 3356, 3356, 3356, 13335}, wantASN: 3356, wantCount: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			asn, count := bgpstuff.DetectPrepending(tc.path)
+			if asn != tc.wantASN || count != tc.wantCount {
+				t.Errorf("Got: %d x%d, Want: %d x%d", asn, count, tc.wantASN, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestContainsPoison(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		path []int
+		want bool
+	}{
+		{name: "clean", path: []int{174, 3356, 13335}},
+		{name: "prepended", path: []int{174, 13335, 13335}},
+		{name: "poisoned", path: []int{174, 13335, 3356, 13335}, want: true},
+		{name: "absent", path: []int{174, 3356}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bgpstuff.ContainsPoison(tc.path, 13335); got != tc.want {
+				t.Errorf("Got: %t, Want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizedInputs(t *testing.T) {
+	t.Parallel()
+	c := bgpstuff.NewBGPClient(true)
+	for _, ip := range []string{"10.1.1.1%eth0", "::FFFF:10.1.1.1", " [FD00::1] "} {
+		t.Run(ip, func(t *testing.T) {
+			_, err := c.GetRoute(ip)
+			if !errors.Is(err, bgpstuff.ErrInvalidIP) {
+				t.Errorf("Expected ErrInvalidIP, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestASPathDetail(t *testing.T) {
+	t.Parallel()
+	path := &bgpstuff.ASPath{Segments: []bgpstuff.ASPathSegment{
+		{Type: bgpstuff.SegmentSequence, ASNs: []bgpstuff.ASN{174, 3356}},
+		{Type: bgpstuff.SegmentSet, ASNs: []bgpstuff.ASN{64500, 64501}},
+	}}
+	if got, want := path.String(), "174 3356 {64500,64501}"; got != want {
+		t.Errorf("Got: %s, Want: %s", got, want)
+	}
+	if got := path.Len(); got != 3 {
+		t.Errorf("Got length: %d, Want: 3", got)
+	}
+	if _, ok := path.Origin(); ok {
+		t.Error("Origin should be ambiguous when the path ends in a set")
+	}
+
+	path.Segments = path.Segments[:1]
+	if origin, ok := path.Origin(); !ok || origin != 3356 {
+		t.Errorf("Got origin: %s %t, Want: AS3356", origin, ok)
+	}
+}
+
+func TestMatchASPath(t *testing.T) {
+	t.Parallel()
+	path := []int{174, 3356, 13335}
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{pattern: "_3356_", want: true},
+		{pattern: "_335_"},
+		{pattern: "^174_", want: true},
+		{pattern: "^3356_"},
+		{pattern: "_13335$", want: true},
+		{pattern: "^174 .* 13335$", want: true},
+		{pattern: "^174 .* 15169$"},
+		{pattern: "^174_3356_13335$", want: true},
+		{pattern: "_(1299|3356)_", want: true},
+		{pattern: "^[0-9]+ [0-9]+ [0-9]+$", want: true},
+		{pattern: "^$"},
+		{pattern: "(unclosed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.pattern, func(t *testing.T) {
+			if got := bgpstuff.MatchASPath(path, tc.pattern); got != tc.want {
+				t.Errorf("Got: %t, Want: %t", got, tc.want)
+			}
+		})
+	}
+	if !bgpstuff.MatchASPath(nil, "^$") {
+		t.Error("Expected the empty path to match ^$")
+	}
+}
+
+func TestPrefixStatsOf(t *testing.T) {
+	t.Parallel()
+	var prefixes []*net.IPNet
+	for _, cidr := range []string{"1.1.1.0/24", "1.0.0.0/24", "1.1.1.128/25", "1.1.1.0/24", "2606:4700::/32", "2606:4700:10::/44", "2a06:98c0::/48"} {
+		_, p, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefixes = append(prefixes, p)
+	}
+
+	got := bgpstuff.PrefixStatsOf(prefixes)
+	want := bgpstuff.PrefixStats{
+		IPv4:          3,
+		IPv6:          3,
+		IPv4Addresses: 512,
+		IPv6Slash48s:  65537,
+		MinIPv4Len:    24,
+		MaxIPv4Len:    25,
+		MinIPv6Len:    32,
+		MaxIPv6Len:    48,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PrefixStatsOf() mismatch (-want +got):\n%s", diff)
+	}
+	if share := got.IPv6Share(); share != 0.5 {
+		t.Errorf("Got IPv6 share: %v, Want: 0.5", share)
+	}
+	if got := bgpstuff.PrefixStatsOf(nil); got != (bgpstuff.PrefixStats{}) {
+		t.Errorf("Got: %+v for no prefixes, Want zero stats", got)
+	}
+}
+
+func TestParseASN(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    bgpstuff.ASN
+		wantDot string
+		wantErr bool
+	}{
+		{in: "13335", want: 13335, wantDot: "AS13335"},
+		{in: "as13335", want: 13335, wantDot: "AS13335"},
+		{in: "AS199964", want: 199964, wantDot: "AS3.3356"},
+		{in: "3.3356", want: 199964, wantDot: "AS3.3356"},
+		{in: "AS0.3356", want: 3356, wantDot: "AS3356"},
+		{in: "65535.65535", want: 4294967295, wantDot: "AS65535.65535"},
+		{in: "65536.1", wantErr: true},
+		{in: "3.", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "4294967296", wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := bgpstuff.ParseASN(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: Expected error, but got %d", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: No error expected, but got error: %v", tc.in, err)
+			continue
+		}
+		if got != tc.want || got.StringDot() != tc.wantDot {
+			t.Errorf("%s: Got: %d (%s), Want: %d (%s)", tc.in, got, got.StringDot(), tc.want, tc.wantDot)
+		}
+	}
+}
+
+func TestASNameFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in         bgpstuff.ASName
+		short, org string
+		country    string
+		formatted  string
+	}{
+		{in: "GOOGLE, US", short: "GOOGLE", org: "GOOGLE", country: "US", formatted: "Google, US"},
+		{in: "LEVEL3", short: "LEVEL3", org: "LEVEL3", formatted: "Level3"},
+		{in: "CLOUDFLARENET - Cloudflare, Inc., US", short: "CLOUDFLARENET", org: "Cloudflare, Inc.", country: "US", formatted: "Cloudflare, Inc., US"},
+		{in: "AMAZON-02 - AMAZON.COM INC, US", short: "AMAZON-02", org: "AMAZON.COM INC", country: "US", formatted: "Amazon.com INC, US"},
+		{in: "Example, Ltd", short: "Example, Ltd", org: "Example, Ltd", formatted: "Example, Ltd"},
+	}
+	f := bgpstuff.NameFormat{Org: true, TitleCase: true, KeepCountry: true}
+	for _, tc := range tests {
+		if got := tc.in.ShortName(); got != tc.short {
+			t.Errorf("%q ShortName: Got: %q, Want: %q", tc.in, got, tc.short)
+		}
+		if got := tc.in.OrgName(); got != tc.org {
+			t.Errorf("%q OrgName: Got: %q, Want: %q", tc.in, got, tc.org)
+		}
+		if got := tc.in.Country(); got != tc.country {
+			t.Errorf("%q Country: Got: %q, Want: %q", tc.in, got, tc.country)
+		}
+		if got := tc.in.Format(f); got != tc.formatted {
+			t.Errorf("%q Format: Got: %q, Want: %q", tc.in, got, tc.formatted)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package bgpstufftest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/bgpstufftest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+	live := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture())
+	dir := t.TempDir()
+	paths := []string{"route/1.1.1.1", "aspath/2606:4700::1111", "asname/4134", "totals"}
+	if err := bgpstufftest.Record(context.Background(), live.URL, dir, paths); err != nil {
+		t.Fatal(err)
+	}
+
+	c := bgpstufftest.StartReplayServer(t, dir).Client()
+	if route, err := c.GetRoute("1.1.1.1"); err != nil || route.String() != "1.1.1.0/24" {
+		t.Errorf("Got route: %v, %v, Want: 1.1.1.0/24", route, err)
+	}
+	if path, _, err := c.GetASPath("2606:4700::1111"); err != nil || len(path) != 2 || path[1] != 13335 {
+		t.Errorf("Got path: %v, %v, Want: [6939 13335]", path, err)
+	}
+	if name, err := c.GetASName(4134); err != nil || name != "CHINANET-BACKBONE" {
+		t.Errorf("Got name: %q, %v, Want: CHINANET-BACKBONE", name, err)
+	}
+	if _, _, err := c.GetTotals(); err != nil {
+		t.Error(err)
+	}
+	// Queries that weren't recorded fail rather than answering nothing.
+	if _, err := c.GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected an error for a query with no recording")
+	}
+}
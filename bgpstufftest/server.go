@@ -0,0 +1,221 @@
+// Package bgpstufftest runs a local stand-in for the bgpstuff.net API, so
+// code using the client can be tested end to end without reaching
+// bgpstuff.net.
+//
+// StartLocalServer answers in the same JSON as bgpstuff.netv2, from a
+// fixture RIB held in memory. It encodes the client's own wire types, so
+// it can't notice the client drifting from the real server; for that,
+// StartReplayServer answers with responses captured from a real server by
+// Record.
+package bgpstufftest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+	"github.com/mellowdrifter/go-bgpstuff.net/api"
+)
+
+// Route is a route in the fixture RIB.
+type Route struct {
+	Prefix netip.Prefix
+	// ASPath is the path to the prefix; the last AS is the origin.
+	ASPath []uint32
+	// ROA is the RPKI state of the route. It defaults to UNKNOWN.
+	ROA bgpstuff.ROAStatus
+}
+
+// Fixture is the data the server answers from.
+type Fixture struct {
+	Routes  []Route
+	ASNames []bgpstuff.ASNumName
+	// Updated is reported as the time of the totals. It defaults to when
+	// the server started.
+	Updated time.Time
+}
+
+// DefaultFixture returns a small RIB covering IPv4 and IPv6 routes in each
+// ROA state, suitable for most tests. Its ASNs are all public, so the
+// client looks them up as it would on bgpstuff.net.
+func DefaultFixture() Fixture {
+	return Fixture{
+		Routes: []Route{
+			{Prefix: netip.MustParsePrefix("1.1.1.0/24"), ASPath: []uint32{174, 13335}, ROA: bgpstuff.ROAValid},
+			{Prefix: netip.MustParsePrefix("8.8.8.0/24"), ASPath: []uint32{3356, 15169}, ROA: bgpstuff.ROAValid},
+			{Prefix: netip.MustParsePrefix("19.0.0.0/8"), ASPath: []uint32{3356, 3389}},
+			{Prefix: netip.MustParsePrefix("23.1.0.0/16"), ASPath: []uint32{174, 4837, 4837, 4134}, ROA: bgpstuff.ROAInvalid},
+			{Prefix: netip.MustParsePrefix("2606:4700::/32"), ASPath: []uint32{6939, 13335}, ROA: bgpstuff.ROAValid},
+		},
+		ASNames: []bgpstuff.ASNumName{
+			{ASN: 13335, ASName: "CLOUDFLARENET", ASLocale: "US"},
+			{ASN: 15169, ASName: "GOOGLE", ASLocale: "US"},
+			{ASN: 3389, ASName: "FORD-ASN", ASLocale: "US"},
+			{ASN: 4134, ASName: "CHINANET-BACKBONE", ASLocale: "CN"},
+		},
+	}
+}
+
+// Server is a running local API.
+type Server struct {
+	// URL is the base URL of the API, for use with bgpstuff.WithBaseURL.
+	URL string
+
+	srv     *httptest.Server
+	fixture Fixture
+}
+
+// StartLocalServer starts a server answering from f. It is shut down when
+// the test finishes.
+func StartLocalServer(t testing.TB, f Fixture) *Server {
+	t.Helper()
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+	s := &Server{fixture: f}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// Client returns a client pointed at the server. Private addresses are
+// allowed so fixtures may use them.
+func (s *Server) Client(opts ...bgpstuff.Option) *bgpstuff.Client {
+	opts = append([]bgpstuff.Option{bgpstuff.WithBaseURL(s.URL), bgpstuff.WithAllowPrivate()}, opts...)
+	return bgpstuff.NewBGPClient(true, opts...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	action, arg := parts[0], ""
+	if len(parts) > 1 {
+		arg = parts[1]
+	}
+
+	d := api.Data{Action: action}
+	switch action {
+	case "route", "origin", "aspath", "roa":
+		addr, err := netip.ParseAddr(arg)
+		if err != nil {
+			http.Error(w, "invalid IP", http.StatusBadRequest)
+			return
+		}
+		d.IP = arg
+		route, ok := s.lookup(addr.Unmap())
+		if !ok {
+			break
+		}
+		d.Exists = true
+		switch action {
+		case "route":
+			d.Route = route.Prefix.String()
+		case "origin":
+			d.Origin = int(origin(route))
+		case "aspath":
+			// The path answer carries the route and origin too.
+			d.Route = route.Prefix.String()
+			d.Origin = int(origin(route))
+			for _, asn := range route.ASPath {
+				d.ASPath = append(d.ASPath, strconv.FormatUint(uint64(asn), 10))
+			}
+		case "roa":
+			d.Route = route.Prefix.String()
+			d.Origin = int(origin(route))
+			d.ROA = string(roa(route))
+		}
+	case "asname":
+		asn, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid ASN", http.StatusBadRequest)
+			return
+		}
+		for _, n := range s.fixture.ASNames {
+			if n.ASN == uint32(asn) {
+				d.ASName, d.ASLocale, d.Exists = n.ASName, n.ASLocale, true
+			}
+		}
+	case "asnames":
+		d.ASNames, d.Exists = s.fixture.ASNames, true
+	case "sourced":
+		asn, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid ASN", http.StatusBadRequest)
+			return
+		}
+		for _, route := range s.fixture.Routes {
+			if origin(route) != uint32(asn) {
+				continue
+			}
+			d.Sourced.Prefixes = append(d.Sourced.Prefixes, route.Prefix.String())
+			if route.Prefix.Addr().Is4() {
+				d.Sourced.Ipv4++
+			} else {
+				d.Sourced.Ipv6++
+			}
+		}
+		d.Exists = len(d.Sourced.Prefixes) > 0
+	case "invalids":
+		byASN := make(map[int][]string)
+		for _, route := range s.fixture.Routes {
+			if roa(route) == bgpstuff.ROAInvalid {
+				asn := int(origin(route))
+				byASN[asn] = append(byASN[asn], route.Prefix.String())
+			}
+		}
+		for asn, prefixes := range byASN {
+			d.Invalids = append(d.Invalids, api.Invalids{ASN: asn, Prefixes: prefixes})
+		}
+		sort.Slice(d.Invalids, func(i, j int) bool { return d.Invalids[i].ASN < d.Invalids[j].ASN })
+		d.Exists = true
+	case "totals":
+		for _, route := range s.fixture.Routes {
+			if route.Prefix.Addr().Is4() {
+				d.Totals.Ipv4++
+			} else {
+				d.Totals.Ipv6++
+			}
+		}
+		d.Totals.Time = uint64(s.fixture.Updated.Unix())
+		d.Exists = true
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(api.Response{Data: d})
+}
+
+// lookup returns the longest route containing addr.
+func (s *Server) lookup(addr netip.Addr) (Route, bool) {
+	var best Route
+	found := false
+	for _, route := range s.fixture.Routes {
+		if route.Prefix.Contains(addr) && (!found || route.Prefix.Bits() > best.Prefix.Bits()) {
+			best, found = route, true
+		}
+	}
+	return best, found
+}
+
+func origin(r Route) uint32 {
+	if len(r.ASPath) == 0 {
+		return 0
+	}
+	return r.ASPath[len(r.ASPath)-1]
+}
+
+func roa(r Route) bgpstuff.ROAStatus {
+	if r.ROA == "" {
+		return bgpstuff.ROAUnknown
+	}
+	return r.ROA
+}
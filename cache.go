@@ -0,0 +1,123 @@
+package bgpstuff
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultCacheSize bounds the number of responses the cache holds.
+const defaultCacheSize = 10000
+
+// responseCache holds decoded responses keyed by request URI. Once it holds
+// size entries, adding another evicts the least recently used.
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	// evicted is called with the endpoint of each evicted entry.
+	evicted func(endpoint string)
+}
+
+type cacheEntry struct {
+	uri      string
+	endpoint string
+	resp     *response
+	fetched  time.Time
+	// negative is set if the response said there was nothing to find.
+	negative bool
+}
+
+func newResponseCache(size int) *responseCache {
+	return &responseCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (rc *responseCache) put(uri string, entry cacheEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry.uri = uri
+	if el, ok := rc.entries[uri]; ok {
+		el.Value = entry
+		rc.order.MoveToFront(el)
+		return
+	}
+	rc.entries[uri] = rc.order.PushFront(entry)
+	for rc.size > 0 && rc.order.Len() > rc.size {
+		oldest := rc.order.Remove(rc.order.Back()).(cacheEntry)
+		delete(rc.entries, oldest.uri)
+		if rc.evicted != nil {
+			rc.evicted(oldest.endpoint)
+		}
+	}
+}
+
+// lookup returns the entry for uri, marking it as recently used. rc.mu
+// must be held.
+func (rc *responseCache) lookup(uri string) (cacheEntry, bool) {
+	el, ok := rc.entries[uri]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	rc.order.MoveToFront(el)
+	return el.Value.(cacheEntry), true
+}
+
+// len returns the number of entries held.
+func (rc *responseCache) len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.order.Len()
+}
+
+// get returns the entry for uri if it is no older than maxAge.
+func (rc *responseCache) get(uri string, maxAge time.Duration) (cacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.lookup(uri)
+	if !ok || time.Since(entry.fetched) > maxAge {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// fresh returns the entry for uri if it is still within its TTL. Negative
+// entries use negativeTTL, everything else uses ttl.
+func (rc *responseCache) fresh(uri string, ttl, negativeTTL time.Duration) (cacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.lookup(uri)
+	if !ok {
+		return cacheEntry{}, false
+	}
+	maxAge := ttl
+	if entry.negative {
+		maxAge = negativeTTL
+	}
+	if time.Since(entry.fetched) > maxAge {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// isNegative reports whether d, the response from endpoint, says there was
+// nothing to find, such as no route for an IP or no name for an ASN.
+func isNegative(endpoint string, d *data) bool {
+	switch endpoint {
+	case "route":
+		return d.Route == ""
+	case "origin", "roa":
+		return d.Origin == 0
+	case "aspath":
+		return len(d.ASPath) == 0
+	case "asname":
+		return d.ASName == ""
+	case "sourced":
+		return len(d.Sourced.Prefixes) == 0
+	}
+	return false
+}
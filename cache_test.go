@@ -0,0 +1,84 @@
+package bgpstuff
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNegativeCache(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantRequests int32
+	}{
+		{name: "default", wantRequests: 3},
+		{name: "enabled", opts: []Option{WithNegativeCacheTTL(time.Minute)}, wantRequests: 1},
+		{name: "disabled", opts: []Option{WithNegativeCacheTTL(0)}, wantRequests: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				fakeAPI(w, r)
+			}, tc.opts...)
+			for i := 0; i < 3; i++ {
+				if route, err := c.GetRoute("19.1.1.1"); err != nil || route != nil {
+					t.Fatalf("Got: %v %v, Want no route", route, err)
+				}
+			}
+			if got := atomic.LoadInt32(&requests); got != tc.wantRequests {
+				t.Errorf("Got %d requests, Want %d", got, tc.wantRequests)
+			}
+		})
+	}
+
+	// Positive answers are not cached unless asked for.
+	var requests int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fakeAPI(w, r)
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetRoute("1.1.1.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Got %d requests, Want 2", got)
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	t.Parallel()
+	var hits int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fakeAPI(w, r)
+	}
+	c := newTestClient(t, handler, WithCacheTTL(time.Minute), WithNegativeCacheTTL(time.Minute), WithCacheSize(2))
+	for _, ip := range []string{"1.1.1.1", "8.8.8.8", "1.1.1.1", "9.9.9.9", "1.1.1.1", "8.8.8.8"} {
+		if _, err := c.GetOrigin(ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 8.8.8.8 was the least recently used when 9.9.9.9 was added, so it is
+	// fetched twice while 1.1.1.1 stays cached.
+	if got := atomic.LoadInt32(&hits); got != 4 {
+		t.Errorf("Got %d requests, Want 4", got)
+	}
+	if got := c.cache.len(); got != 2 {
+		t.Errorf("Got %d entries, Want 2", got)
+	}
+
+	rec := httptest.NewRecorder()
+	c.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `bgpstuff_cache_evictions_total{endpoint="origin"} 2`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Metrics missing %q:\n%s", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,198 @@
+// Package chatops answers chat commands such as "!route 1.1.1.1" using a
+// bgpstuff client. It deals only in plain strings so it can sit behind any
+// Slack, IRC or Matrix bot framework: pass each incoming message to
+// Bot.Handle with the request context and send back the reply if there is one.
+package chatops
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+// DefaultPrefix is the command prefix used by New.
+const DefaultPrefix = "!"
+
+// Bot turns chat messages into bgpstuff lookups.
+type Bot struct {
+	client   *bgpstuff.Client
+	prefix   string
+	commands map[string]command
+}
+
+type command struct {
+	usage string
+	run   func(c *bgpstuff.Client, arg string, opts ...bgpstuff.CallOption) (string, error)
+}
+
+// New returns a Bot using the DefaultPrefix.
+func New(c *bgpstuff.Client) *Bot {
+	return NewWithPrefix(c, DefaultPrefix)
+}
+
+// NewWithPrefix returns a Bot that only answers messages starting with prefix.
+func NewWithPrefix(c *bgpstuff.Client, prefix string) *Bot {
+	return &Bot{
+		client: c,
+		prefix: prefix,
+		commands: map[string]command{
+			"route":   {"route <ip>", route},
+			"origin":  {"origin <ip>", origin},
+			"aspath":  {"aspath <ip>", aspath},
+			"roa":     {"roa <ip>", roa},
+			"asname":  {"asname <asn>", asname},
+			"sourced": {"sourced <asn>", sourced},
+			"totals":  {"totals", totals},
+			"report":  {"report <ip>", report},
+		},
+	}
+}
+
+// Handle answers a single chat message. The second return value is false
+// if the message is not a command for this bot, in which case it should be
+// ignored. Lookup errors are returned as the reply so users can see them.
+// Lookups run at PriorityHigh, ahead of any background work sharing the
+// client, and are abandoned if ctx is done.
+func (b *Bot) Handle(ctx context.Context, message string) (string, bool) {
+	message = strings.TrimSpace(message)
+	if !strings.HasPrefix(message, b.prefix) {
+		return "", false
+	}
+	fields := strings.Fields(strings.TrimPrefix(message, b.prefix))
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	name := strings.ToLower(fields[0])
+	if name == "help" {
+		return b.help(), true
+	}
+	cmd, ok := b.commands[name]
+	if !ok {
+		return "", false
+	}
+
+	var arg string
+	if len(fields) > 1 {
+		arg = fields[1]
+	}
+	if strings.Contains(cmd.usage, "<") && arg == "" {
+		return fmt.Sprintf("usage: %s%s", b.prefix, cmd.usage), true
+	}
+
+	reply, err := cmd.run(b.client, arg,
+		bgpstuff.WithContext(ctx), bgpstuff.WithPriority(bgpstuff.PriorityHigh))
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), true
+	}
+	return reply, true
+}
+
+func (b *Bot) help() string {
+	usages := make([]string, 0, len(b.commands))
+	for _, cmd := range b.commands {
+		usages = append(usages, b.prefix+cmd.usage)
+	}
+	sort.Strings(usages)
+	return "commands: " + strings.Join(usages, ", ")
+}
+
+// parseASN accepts an AS number with or without an "AS" prefix. A bad
+// argument is reported the same way the client reports its lookup errors.
+func parseASN(endpoint, arg string) (int, error) {
+	asn, err := bgpstuff.ParseASN(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%s lookup for %q: %w", endpoint, arg, err)
+	}
+	return int(asn), nil
+}
+
+func route(c *bgpstuff.Client, ip string, opts ...bgpstuff.CallOption) (string, error) {
+	prefix, err := c.GetRoute(ip, opts...)
+	if err != nil {
+		return "", err
+	}
+	if prefix == nil {
+		return fmt.Sprintf("no route for %s", ip), nil
+	}
+	return fmt.Sprintf("route for %s is %s", ip, prefix), nil
+}
+
+func origin(c *bgpstuff.Client, ip string, opts ...bgpstuff.CallOption) (string, error) {
+	asn, err := c.GetOrigin(ip, opts...)
+	if err != nil {
+		return "", err
+	}
+	if asn == 0 {
+		return fmt.Sprintf("no origin for %s", ip), nil
+	}
+	return fmt.Sprintf("origin for %s is AS%d", ip, asn), nil
+}
+
+func aspath(c *bgpstuff.Client, ip string, opts ...bgpstuff.CallOption) (string, error) {
+	path, set, err := c.GetASPath(ip, opts...)
+	if err != nil {
+		return "", err
+	}
+	if len(path) == 0 {
+		return fmt.Sprintf("no AS path for %s", ip), nil
+	}
+	reply := fmt.Sprintf("AS path for %s is %s", ip, strings.Trim(fmt.Sprint(path), "[]"))
+	if len(set) > 0 {
+		reply += fmt.Sprintf(" {%s}", strings.Trim(fmt.Sprint(set), "[]"))
+	}
+	return reply, nil
+}
+
+func roa(c *bgpstuff.Client, ip string, opts ...bgpstuff.CallOption) (string, error) {
+	status, err := c.GetROA(ip, opts...)
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		return fmt.Sprintf("no ROA status for %s", ip), nil
+	}
+	return fmt.Sprintf("ROA status for %s is %s", ip, status), nil
+}
+
+func asname(c *bgpstuff.Client, arg string, opts ...bgpstuff.CallOption) (string, error) {
+	asn, err := parseASN("asname", arg)
+	if err != nil {
+		return "", err
+	}
+	name, err := c.GetASName(asn, opts...)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return fmt.Sprintf("no name for AS%d", asn), nil
+	}
+	return fmt.Sprintf("AS%d is %s", asn, name), nil
+}
+
+func sourced(c *bgpstuff.Client, arg string, opts ...bgpstuff.CallOption) (string, error) {
+	asn, err := parseASN("sourced", arg)
+	if err != nil {
+		return "", err
+	}
+	_, v4, v6, err := c.GetSourced(asn, opts...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("AS%d sources %d IPv4 and %d IPv6 prefixes", asn, v4, v6), nil
+}
+
+func totals(c *bgpstuff.Client, _ string, opts ...bgpstuff.CallOption) (string, error) {
+	v4, v6, err := c.GetTotals(opts...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d IPv4 and %d IPv6 prefixes in the table", v4, v6), nil
+}
+
+func report(c *bgpstuff.Client, ip string, opts ...bgpstuff.CallOption) (string, error) {
+	return c.Report(ip, opts...)
+}
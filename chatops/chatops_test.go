@@ -0,0 +1,39 @@
+package chatops_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+	"github.com/mellowdrifter/go-bgpstuff.net/chatops"
+)
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		message   string
+		wantReply string
+		wantOK    bool
+	}{
+		{message: "hello there"},
+		{message: "!"},
+		{message: "!unknown 1.1.1.1"},
+		{message: "!route", wantReply: "usage: !route <ip>", wantOK: true},
+		{message: "!route 10.1.1.1", wantReply: `error: route lookup for "10.1.1.1": invalid IP`, wantOK: true},
+		{message: "!asname ASfoo", wantReply: `error: asname lookup for "ASfoo": invalid AS Number`, wantOK: true},
+		{message: "!help", wantReply: "commands: !asname <asn>", wantOK: true},
+	}
+	b := chatops.New(bgpstuff.NewBGPClient(true))
+	for _, tc := range tests {
+		t.Run(tc.message, func(t *testing.T) {
+			reply, ok := b.Handle(context.Background(), tc.message)
+			if ok != tc.wantOK {
+				t.Errorf("Got ok: %t, Want: %t", ok, tc.wantOK)
+			}
+			if !strings.HasPrefix(reply, tc.wantReply) {
+				t.Errorf("Got: %q, Want prefix: %q", reply, tc.wantReply)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package bgpstuff
+
+import (
+	"net"
+
+	"github.com/mellowdrifter/bogons"
+)
+
+// AddressClass describes what kind of address an IP is.
+type AddressClass int
+
+// Address classes returned by Classify.
+const (
+	ClassInvalid       AddressClass = iota // not an IP address at all
+	ClassPublic                            // routable on the public internet
+	ClassUnspecified                       // 0.0.0.0/8, ::/128
+	ClassLoopback                          // 127.0.0.0/8, ::1/128
+	ClassPrivate                           // RFC1918, RFC4193
+	ClassCGNAT                             // RFC6598
+	ClassLinkLocal                         // RFC3927, RFC4291
+	ClassMulticast                         // 224.0.0.0/4, ff00::/8
+	ClassDocumentation                     // RFC5737, RFC3849
+	ClassBenchmark                         // RFC2544
+	ClassProtocol                          // RFC6890 IETF protocol assignments
+	ClassTeredo                            // RFC4380
+	Class6to4                              // RFC3056
+	ClassReserved                          // anything else that is not public
+)
+
+var addressClassNames = map[AddressClass]string{
+	ClassInvalid:       "Invalid",
+	ClassPublic:        "Public",
+	ClassUnspecified:   "Unspecified",
+	ClassLoopback:      "Loopback",
+	ClassPrivate:       "Private",
+	ClassCGNAT:         "CGNAT",
+	ClassLinkLocal:     "LinkLocal",
+	ClassMulticast:     "Multicast",
+	ClassDocumentation: "Documentation",
+	ClassBenchmark:     "Benchmark",
+	ClassProtocol:      "Protocol",
+	ClassTeredo:        "Teredo",
+	Class6to4:          "6to4",
+	ClassReserved:      "Reserved",
+}
+
+func (a AddressClass) String() string {
+	if name, ok := addressClassNames[a]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+type classRange struct {
+	prefix *net.IPNet
+	class  AddressClass
+}
+
+// classRanges is checked in order, so more specific ranges come first.
+var classRanges = func() []classRange {
+	ranges := []struct {
+		cidr  string
+		class AddressClass
+	}{
+		{"0.0.0.0/8", ClassUnspecified},
+		{"127.0.0.0/8", ClassLoopback},
+		{"10.0.0.0/8", ClassPrivate},
+		{"172.16.0.0/12", ClassPrivate},
+		{"192.168.0.0/16", ClassPrivate},
+		{"100.64.0.0/10", ClassCGNAT},
+		{"169.254.0.0/16", ClassLinkLocal},
+		{"192.0.0.0/24", ClassProtocol},
+		{"192.0.2.0/24", ClassDocumentation},
+		{"198.51.100.0/24", ClassDocumentation},
+		{"203.0.113.0/24", ClassDocumentation},
+		{"198.18.0.0/15", ClassBenchmark},
+		{"224.0.0.0/4", ClassMulticast},
+		{"::/128", ClassUnspecified},
+		{"::1/128", ClassLoopback},
+		{"fc00::/7", ClassPrivate},
+		{"fe80::/10", ClassLinkLocal},
+		{"ff00::/8", ClassMulticast},
+		{"2001:db8::/32", ClassDocumentation},
+		{"2001::/32", ClassTeredo},
+		{"2002::/16", Class6to4},
+	}
+	out := make([]classRange, 0, len(ranges))
+	for _, r := range ranges {
+		_, ipnet, _ := net.ParseCIDR(r.cidr)
+		out = append(out, classRange{prefix: ipnet, class: r.class})
+	}
+	return out
+}()
+
+// Classify returns the class of the address. A prefix is classed by its
+// network address, as lookups do. An argument is ClassPublic exactly when a
+// client without WithAllowPrivate would accept it as a lookup argument, so
+// callers can pre-filter their inputs with the same rules GetRoute uses.
+func Classify(ip string) AddressClass {
+	normalized, ok := normalizeArg(ip)
+	if !ok {
+		return ClassInvalid
+	}
+	p := net.ParseIP(normalized)
+	if bogons.IsPublicIP(p) {
+		return ClassPublic
+	}
+	for _, r := range classRanges {
+		if r.prefix.Contains(p) {
+			return r.class
+		}
+	}
+	return ClassReserved
+}
@@ -0,0 +1,457 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client pointed at a local server using handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewBGPClient(true, opts...)
+	c.api = srv.URL
+	return c
+}
+
+// fakeAPI answers the lookups for 1.1.1.1 the way bgpstuff.net does.
+// Any other path returns an empty response.
+func fakeAPI(w http.ResponseWriter, r *http.Request) {
+	responses := map[string]string{
+		"/route/1.1.1.1":  `{"Response":{"Action":"route","Route":"1.1.1.0/24","Exists":true}}`,
+		"/origin/1.1.1.1": `{"Response":{"Action":"origin","Origin":"13335","Exists":true}}`,
+		"/aspath/1.1.1.1": `{"Response":{"Action":"aspath","ASPath":["174","13335"],"Route":"1.1.1.0/24","Origin":"13335","Exists":true}}`,
+		"/roa/1.1.1.1":    `{"Response":{"Action":"roa","ROA":"VALID","Origin":"13335","Route":"1.1.1.0/24","Exists":true}}`,
+		"/asname/13335":   `{"Response":{"Action":"asname","ASName":"CLOUDFLARENET","ASLocale":"US","Exists":true}}`,
+	}
+	if resp, ok := responses[r.URL.Path]; ok {
+		fmt.Fprint(w, resp)
+		return
+	}
+	fmt.Fprint(w, `{"Response":{"Origin":"0"}}`)
+}
+
+func TestGetASNamesFor(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+	got, err := c.GetASNamesFor([]int{13335, 13335, 3356})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]string{13335: "CLOUDFLARENET"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %v, Want: %v", got, want)
+	}
+
+	if _, err := c.GetASNamesFor([]int{13335, 0}); !errors.Is(err, ErrInvalidASN) {
+		t.Errorf("Expected ErrInvalidASN, got: %v", err)
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ip     string
+		want   string
+		wantOK bool
+	}{
+		{ip: "1.1.1.1", want: "1.1.1.1", wantOK: true},
+		{ip: "::ffff:1.1.1.1", want: "1.1.1.1", wantOK: true},
+		{ip: "2600:0000::ABCD", want: "2600::abcd", wantOK: true},
+		{ip: "2600::1%eth0", want: "2600::1", wantOK: true},
+		{ip: "[2600::1]", want: "2600::1", wantOK: true},
+		{ip: " 1.1.1.1\n", want: "1.1.1.1", wantOK: true},
+		{ip: "🥺"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			got, ok := normalizeIP(tc.ip)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("Got: %q %t, Want: %q %t", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	t.Parallel()
+	var down int32
+	handler := downAPI(&down, fakeAPI)
+
+	c := newTestClient(t, handler, WithStaleIfError(time.Hour))
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&down, apiDown)
+
+	var meta Meta
+	origin, err := c.GetOrigin("1.1.1.1", WithMeta(&meta))
+	if err != nil {
+		t.Fatalf("No error expected, but got error: %v", err)
+	}
+	if origin != 13335 || !meta.Stale {
+		t.Errorf("Got: %d stale=%t, Want: 13335 stale=true", origin, meta.Stale)
+	}
+
+	// Without the option, the error comes straight back.
+	c = newTestClient(t, handler)
+	if _, err := c.GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected error, but no error returned")
+	}
+}
+
+// Values of the switch read by downAPI.
+const (
+	apiUp int32 = iota
+	apiDown
+	apiGone
+)
+
+// downAPI answers with next while *state is apiUp. Once it is apiDown every
+// request fails with 502 Bad Gateway, as when the API is unreachable, and
+// once it is apiGone with 404 Not Found.
+func downAPI(state *int32, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.LoadInt32(state) {
+		case apiDown:
+			http.Error(w, "down", http.StatusBadGateway)
+		case apiGone:
+			http.Error(w, "gone", http.StatusNotFound)
+		default:
+			next(w, r)
+		}
+	}
+}
+
+// countingLimiter lets every request through, counting them.
+type countingLimiter struct{ waits int32 }
+
+func (l *countingLimiter) Wait(context.Context) error {
+	atomic.AddInt32(&l.waits, 1)
+	return nil
+}
+
+// slowLimiter makes every request wait for d.
+type slowLimiter struct{ d time.Duration }
+
+func (l slowLimiter) Wait(ctx context.Context) error {
+	time.Sleep(l.d)
+	return nil
+}
+
+func TestParseRoute(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		route string
+		ip    string
+		want  RouteResult
+	}{
+		{route: "", ip: "19.1.1.1", want: RouteResult{}},
+		{route: "1.1.1.0/24", ip: "1.1.1.1", want: RouteResult{Prefix: netip.MustParsePrefix("1.1.1.0/24"), Exists: true}},
+		{route: "/0", ip: "1.1.1.1", want: RouteResult{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Exists: true, Default: true}},
+		{route: "/0", ip: "2600::", want: RouteResult{Prefix: netip.MustParsePrefix("::/0"), Exists: true, Default: true}},
+		{route: "::/0", ip: "2600::", want: RouteResult{Prefix: netip.MustParsePrefix("::/0"), Exists: true, Default: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.route, func(t *testing.T) {
+			got, err := parseRoute(tc.route, tc.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tc.want {
+				t.Errorf("Got: %+v, Want: %+v", *got, tc.want)
+			}
+		})
+	}
+	if _, err := parseRoute("garbage", "1.1.1.1"); err == nil {
+		t.Error("Expected error, but no error returned")
+	}
+}
+
+func TestGetInvalidsFor(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Response":{"Action":"invalids","IP":"","Invalids":[`+
+			`{"ASN":"13335","Prefixes":["1.1.1.0/25","1.1.1.128/25"]},`+
+			`{"ASN":"3356","Prefixes":["4.0.0.0/25"]}`+
+			`],"Exists":true}}`)
+	})
+	if err := c.GetInvalidsFor([]int{13335}); err != nil {
+		t.Fatal(err)
+	}
+	got := c.InvalidTable()
+	if len(got) != 1 || len(got[13335]) != 2 {
+		t.Errorf("Got: %v, Want only AS13335's two prefixes", got)
+	}
+
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.InvalidTable(); len(got) != 2 {
+		t.Errorf("Got: %v, Want both ASNs", got)
+	}
+}
+
+func TestInputs(t *testing.T) {
+	t.Parallel()
+	strict := newTestClient(t, fakeAPI)
+	private := newTestClient(t, fakeAPI, WithAllowPrivate())
+	route := func(c *Client, ip string) func() error {
+		return func() error { _, err := c.GetRoute(ip); return err }
+	}
+	tests := []struct {
+		name    string
+		lookup  func() error
+		wantErr error
+	}{
+		{name: "private address", lookup: route(strict, "10.1.1.1"), wantErr: ErrInvalidIP},
+		{name: "private prefix", lookup: route(strict, "10.0.0.0/8"), wantErr: ErrInvalidIP},
+		{name: "private ASN", lookup: func() error { _, err := strict.GetASName(64512); return err }, wantErr: ErrInvalidASN},
+		{name: "bad prefix length", lookup: func() error { _, err := strict.GetROA("1.1.1.1/33"); return err }, wantErr: ErrInvalidIP},
+		{name: "host prefix", lookup: func() error {
+			if origin, err := strict.GetOrigin("1.1.1.1/32"); err != nil || origin != 13335 {
+				return fmt.Errorf("got origin %d, %v, Want: 13335", origin, err)
+			}
+			return nil
+		}},
+		{name: "allowed private address", lookup: route(private, "10.1.1.1")},
+		{name: "allowed private ASN", lookup: func() error { _, err := private.GetASName(64512); return err }},
+		{name: "not an address", lookup: route(private, "🥺"), wantErr: ErrInvalidIP},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.lookup()
+			if tc.wantErr == nil && err != nil {
+				t.Errorf("No error expected, but got error: %v", err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUnexpectedResponse(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Every answer claims to be a route.
+		fmt.Fprint(w, `{"Response":{"Action":"route","Route":"1.1.1.0/24","Origin":"13335","Invalids":[]}}`)
+	}, WithCacheTTL(time.Hour))
+
+	if _, err := c.GetOrigin("1.1.1.1"); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("Got: %v, Want: %v", err, ErrUnexpectedResponse)
+	}
+	if err := c.GetInvalids(); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("Got: %v, Want: %v", err, ErrUnexpectedResponse)
+	}
+	// A mismatched answer is not cached for later calls.
+	if _, ok := c.cache.get(c.getURI([]string{"origin", "1.1.1.1"}), time.Hour); ok {
+		t.Error("Mismatched answer was cached")
+	}
+	if _, err := c.GetRoute("1.1.1.1"); err != nil {
+		t.Errorf("No error expected, but got error: %v", err)
+	}
+}
+
+func TestASPathBadASN(t *testing.T) {
+	t.Parallel()
+	tests := []string{
+		`{"Response":{"Action":"aspath","ASPath":["174","13x35"]}}`,
+		`{"Response":{"Action":"aspath","ASPath":["174","13335"],"ASSet":["-1"]}}`,
+		`{"Response":{"Action":"aspath","ASPath":["174","4294967296"]}}`,
+	}
+	for _, body := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		})
+		path, set, err := c.GetASPath("1.1.1.1")
+		if err == nil {
+			t.Errorf("Expected error for %s, but got path %v set %v", body, path, set)
+		}
+	}
+}
+
+func TestEnvAPI(t *testing.T) {
+	t.Setenv(EnvAPI, "http://bgpstuff.example:8080/")
+	if got := NewBGPClient(false).api; got != "http://bgpstuff.example:8080" {
+		t.Errorf("Got: %s, Want the URL from %s", got, EnvAPI)
+	}
+	if got := NewBGPClient(true, WithBaseURL("http://other.example")).api; got != "http://other.example" {
+		t.Errorf("Got: %s, Want WithBaseURL to take priority", got)
+	}
+
+	t.Setenv(EnvAPI, "")
+	if got := NewBGPClient(false).api; got != LiveAPI {
+		t.Errorf("Got: %s, Want: %s", got, LiveAPI)
+	}
+}
+
+func TestExists(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+
+	if ok, err := c.RouteExists("1.1.1.1"); err != nil || !ok {
+		t.Errorf("RouteExists(1.1.1.1) = %v, %v, Want: true", ok, err)
+	}
+	if ok, err := c.RouteExists("8.8.8.8"); err != nil || ok {
+		t.Errorf("RouteExists(8.8.8.8) = %v, %v, Want: false", ok, err)
+	}
+	if _, err := c.RouteExists("banana"); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("RouteExists(banana) error = %v, Want: %v", err, ErrInvalidIP)
+	}
+	if ok, err := c.ASNExists(13335); err != nil || !ok {
+		t.Errorf("ASNExists(13335) = %v, %v, Want: true", ok, err)
+	}
+	if ok, err := c.ASNExists(15169); err != nil || ok {
+		t.Errorf("ASNExists(15169) = %v, %v, Want: false", ok, err)
+	}
+}
+
+func TestIsOriginatedBy(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Response":{"Action":"sourced","Sourced":{"Prefixes":["1.1.1.0/24","2606:4700::/32"],"Ipv4":1,"Ipv6":1},"Exists":true}}`)
+	})
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{prefix: "1.1.1.0/24", want: true},
+		{prefix: "1.1.1.128/25", want: true},
+		{prefix: "1.1.1.1/32", want: true},
+		{prefix: "1.1.0.0/16", want: false},
+		{prefix: "8.8.8.0/24", want: false},
+		{prefix: "2606:4700:10::/48", want: true},
+		{prefix: "2001:db8::/32", want: false},
+	}
+	for _, tc := range tests {
+		got, err := c.IsOriginatedBy(netip.MustParsePrefix(tc.prefix), 13335)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("IsOriginatedBy(%s) = %v, Want: %v", tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestInvalidCountsOnly(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"Response":{"Action":"invalids","Invalids":[`+
+			`{"ASN":"13335","Prefixes":["1.1.1.0/25","1.1.1.128/25"]},`+
+			`{"ASN":"174","Prefixes":["23.1.0.0/16"]}]}}`)
+	}, WithInvalidCountsOnly())
+
+	if _, err := c.InvalidCount(13335); !errors.As(err, new(ErrCacheCold)) {
+		t.Errorf("Got error: %v, Want: ErrCacheCold", err)
+	}
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.InvalidCount(13335); err != nil || got != 2 {
+		t.Errorf("InvalidCount(13335) = %d, %v, Want: 2", got, err)
+	}
+	if got, err := c.InvalidCount(15169); err != nil || got != 0 {
+		t.Errorf("InvalidCount(15169) = %d, %v, Want: 0", got, err)
+	}
+	if got := c.TopInvalidASNs(1); len(got) != 1 || got[0].ASN != 13335 || got[0].Count != 2 {
+		t.Errorf("TopInvalidASNs(1) = %+v, Want AS13335 with 2", got)
+	}
+	if c.InvalidTable() != nil {
+		t.Error("Expected no full invalids table")
+	}
+
+	// Prefixes are fetched on demand, and only for ASNs with invalids.
+	before := atomic.LoadInt32(&requests)
+	if got, err := c.GetInvalid(15169); err != nil || got != nil {
+		t.Errorf("GetInvalid(15169) = %v, %v, Want: nil", got, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != before {
+		t.Errorf("Got %d requests for an ASN without invalids, Want: 0", got-before)
+	}
+	got, err := c.GetInvalid(13335)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].String() != "1.1.1.0/25" {
+		t.Errorf("GetInvalid(13335) = %v, Want: [1.1.1.0/25 1.1.1.128/25]", got)
+	}
+
+	// They are kept until the counts are loaded again, and several ASNs
+	// can be fetched in one go.
+	before = atomic.LoadInt32(&requests)
+	if err := c.GetInvalidsFor([]int{13335, 174}); err != nil {
+		t.Fatal(err)
+	}
+	for _, asn := range []int{13335, 13335, 174} {
+		if got, err := c.GetInvalid(asn); err != nil || len(got) == 0 {
+			t.Errorf("GetInvalid(%d) = %v, %v, Want prefixes", asn, got, err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests) - before; got != 1 {
+		t.Errorf("Got %d requests, Want 1", got)
+	}
+	if c.InvalidTable() != nil {
+		t.Error("Expected no full invalids table after GetInvalidsFor")
+	}
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	before = atomic.LoadInt32(&requests)
+	if _, err := c.GetInvalid(13335); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&requests) - before; got != 1 {
+		t.Errorf("Got %d requests after reloading the counts, Want 1", got)
+	}
+}
+
+func TestNonJSONResponse(t *testing.T) {
+	t.Parallel()
+	page := "<html>\n<head><title>502 Bad Gateway</title></head>\n<body>cloudflare</body>\n</html>"
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+	}{
+		{name: "html 200", status: http.StatusOK, contentType: "text/html; charset=utf-8"},
+		{name: "html 502", status: http.StatusBadGateway, contentType: "text/html"},
+		{name: "unlabelled", status: http.StatusOK, contentType: "text/plain"},
+	}
+	for _, tc := range tests {
+		var html int32 = 1
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&html) == 0 {
+				fakeAPI(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", tc.contentType)
+			w.WriteHeader(tc.status)
+			fmt.Fprint(w, page)
+		}, WithCacheTTL(time.Hour))
+
+		_, err := c.GetOrigin("1.1.1.1")
+		if !errors.Is(err, ErrNonJSONResponse) || !strings.Contains(err.Error(), "502 Bad Gateway") {
+			t.Errorf("%s: Got error: %v, Want: %v with the page title", tc.name, err, ErrNonJSONResponse)
+		}
+
+		// Nothing was cached from the page.
+		atomic.StoreInt32(&html, 0)
+		if origin, err := c.GetOrigin("1.1.1.1"); err != nil || origin != 13335 {
+			t.Errorf("%s: Got: %d, %v after the page, Want: 13335", tc.name, origin, err)
+		}
+	}
+}
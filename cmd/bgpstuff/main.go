@@ -0,0 +1,288 @@
+// Command bgpstuff looks up IP addresses, prefixes and ASNs on
+// bgpstuff.net.
+//
+//	bgpstuff 1.1.1.1 AS13335
+//
+// With -stdin it reads one query per line instead and writes a record for
+// each, in the order they were read, so it can enrich data in a pipeline:
+//
+//	cut -d' ' -f1 access.log | bgpstuff -stdin -format csv
+//
+// The exit status says how the queries went, taking the worst of them:
+//
+//	0  every query was answered
+//	1  a query had no answer, such as an IP with no route
+//	2  a query or flag was not valid
+//	3  the API could not answer, or output could not be written
+//
+// With -quiet only the answers are printed: the route and origin of an IP
+// or prefix, and the name of an ASN. Errors are left out and only the exit
+// status reports them. Records written as NDJSON or CSV carry an
+// error_kind of not_found, invalid_input or api_error.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+// Exit statuses.
+const (
+	exitOK = iota
+	exitNotFound
+	exitInvalid
+	exitError
+)
+
+// Kinds of error in records.
+const (
+	errNotFound = "not_found"
+	errInvalid  = "invalid_input"
+	errAPI      = "api_error"
+)
+
+func main() {
+	os.Exit(cli())
+}
+
+func cli() int {
+	log.SetFlags(0)
+	log.SetPrefix("bgpstuff: ")
+	stdin := flag.Bool("stdin", false, "read queries from stdin, one per line")
+	format := flag.String("format", "", "output format: text, ndjson or csv (default text, or ndjson with -stdin)")
+	workers := flag.Int("workers", 4, "queries to run at once")
+	testing := flag.Bool("test", false, "use the test API")
+	quiet := flag.Bool("quiet", false, "print only answers, and no errors")
+	flag.Parse()
+
+	if *format == "" {
+		*format = "text"
+		if *stdin {
+			*format = "ndjson"
+		}
+	}
+	w, err := newWriter(os.Stdout, *format, *quiet)
+	if err != nil {
+		log.Print(err)
+		return exitInvalid
+	}
+
+	queries := make(chan string)
+	go func() {
+		defer close(queries)
+		if !*stdin {
+			for _, arg := range flag.Args() {
+				queries <- arg
+			}
+			return
+		}
+		in := bufio.NewScanner(os.Stdin)
+		for in.Scan() {
+			if q := strings.TrimSpace(in.Text()); q != "" {
+				queries <- q
+			}
+		}
+		if err := in.Err(); err != nil {
+			log.Print(err)
+		}
+	}()
+
+	c := bgpstuff.NewBGPClient(*testing)
+	status := exitOK
+	emit := func(r record) error {
+		if r.status > status {
+			status = r.status
+		}
+		return w.write(r)
+	}
+	if err := run(c, queries, *workers, emit); err != nil {
+		log.Print(err)
+		return exitError
+	}
+	if err := w.flush(); err != nil {
+		log.Print(err)
+		return exitError
+	}
+	return status
+}
+
+// record is the answer to one query.
+type record struct {
+	Query  string `json:"query"`
+	Kind   string `json:"kind,omitempty"`
+	Route  string `json:"route,omitempty"`
+	Origin int    `json:"origin,omitempty"`
+	ASName string `json:"as_name,omitempty"`
+	ASPath []int  `json:"as_path,omitempty"`
+	ROA    string `json:"roa,omitempty"`
+	IPv4   int    `json:"ipv4,omitempty"`
+	IPv6   int    `json:"ipv6,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// ErrorKind is errNotFound, errInvalid or errAPI if the query failed.
+	ErrorKind string `json:"error_kind,omitempty"`
+
+	// text is the human readable answer, and answer the bare answer
+	// printed with -quiet.
+	text   string
+	answer string
+	// status is the exit status the query calls for.
+	status int
+}
+
+// query looks up q, which may be an IP, a prefix or an ASN.
+func query(c *bgpstuff.Client, q string) record {
+	rec := record{Query: q}
+	res, err := c.Query(q)
+	if err != nil {
+		rec.Error = err.Error()
+		rec.text = err.Error()
+		rec.ErrorKind, rec.status = errAPI, exitError
+		if errors.Is(err, bgpstuff.ErrInvalidIP) || errors.Is(err, bgpstuff.ErrInvalidASN) {
+			rec.ErrorKind, rec.status = errInvalid, exitInvalid
+		}
+		return rec
+	}
+	rec.Kind = res.Kind.String()
+	if ip := res.IP; ip != nil {
+		rec.Route = ip.Route
+		rec.Origin = ip.Origin
+		rec.ASName = ip.ASName
+		rec.ASPath = ip.ASPath
+		rec.ROA = ip.ROA
+		rec.text = ip.String()
+		if ip.Route != "" {
+			rec.answer = fmt.Sprintf("%s %d", ip.Route, ip.Origin)
+		}
+	}
+	if as := res.AS; as != nil {
+		rec.Origin = int(as.ASN)
+		rec.ASName = as.Name
+		rec.IPv4 = as.IPv4
+		rec.IPv6 = as.IPv6
+		rec.text = fmt.Sprintf("%s %s: %d IPv4 and %d IPv6 prefixes\n", as.ASN, as.Name, as.IPv4, as.IPv6)
+		if as.Name != "" || as.IPv4+as.IPv6 > 0 {
+			rec.answer = as.Name
+		}
+	}
+	if rec.answer == "" {
+		rec.Error = "not found"
+		rec.ErrorKind, rec.status = errNotFound, exitNotFound
+	}
+	return rec
+}
+
+// run looks up each query with workers running at once, and hands the
+// records to emit in the order the queries came in.
+func run(c *bgpstuff.Client, queries <-chan string, workers int, emit func(record) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	type job struct {
+		q   string
+		out chan record
+	}
+	jobs := make(chan job, workers)
+	pending := make(chan chan record, workers)
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for q := range queries {
+			out := make(chan record, 1)
+			jobs <- job{q: q, out: out}
+			pending <- out
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.out <- query(c, j.q)
+			}
+		}()
+	}
+
+	for out := range pending {
+		if err := emit(<-out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writer writes records in one of the output formats.
+type writer struct {
+	write func(record) error
+	flush func() error
+}
+
+// newWriter returns a writer for format. Quiet text output has only the
+// answers; quiet NDJSON and CSV are as usual.
+func newWriter(w io.Writer, format string, quiet bool) (*writer, error) {
+	switch format {
+	case "text":
+		return &writer{
+			write: func(r record) error {
+				if quiet {
+					if r.answer == "" {
+						return nil
+					}
+					_, err := fmt.Fprintln(w, r.answer)
+					return err
+				}
+				_, err := io.WriteString(w, r.text)
+				if err == nil && !strings.HasSuffix(r.text, "\n") {
+					_, err = io.WriteString(w, "\n")
+				}
+				return err
+			},
+			flush: func() error { return nil },
+		}, nil
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		return &writer{
+			write: func(r record) error { return enc.Encode(r) },
+			flush: func() error { return nil },
+		}, nil
+	case "csv":
+		out := csv.NewWriter(w)
+		header := []string{"query", "kind", "route", "origin", "as_name", "as_path", "roa", "ipv4", "ipv6", "error", "error_kind"}
+		if err := out.Write(header); err != nil {
+			return nil, err
+		}
+		return &writer{
+			write: func(r record) error {
+				path := make([]string, len(r.ASPath))
+				for i, asn := range r.ASPath {
+					path[i] = strconv.Itoa(asn)
+				}
+				return out.Write([]string{
+					r.Query, r.Kind, r.Route, itoa(r.Origin), r.ASName,
+					strings.Join(path, " "), r.ROA, itoa(r.IPv4), itoa(r.IPv6), r.Error, r.ErrorKind,
+				})
+			},
+			flush: func() error {
+				out.Flush()
+				return out.Error()
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// itoa formats n, leaving 0 empty.
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/bgpstufftest"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	c := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture()).Client()
+
+	tests := []struct {
+		format string
+		quiet  bool
+		want   string
+	}{
+		{
+			format: "csv",
+			want: `query,kind,route,origin,as_name,as_path,roa,ipv4,ipv6,error,error_kind
+1.1.1.1,ip,1.1.1.0/24,13335,CLOUDFLARENET,174 13335,VALID,,,,
+AS15169,asn,,15169,GOOGLE,,,1,,,
+banana,,,,,,,,,"query lookup for ""banana"": invalid AS Number",invalid_input
+9.9.9.9,ip,,,,,,,,not found,not_found
+8.8.8.0/24,prefix,8.8.8.0/24,15169,GOOGLE,3356 15169,VALID,,,,
+`,
+		},
+		{
+			format: "text",
+			quiet:  true,
+			want: `1.1.1.0/24 13335
+GOOGLE
+8.8.8.0/24 15169
+`,
+		},
+	}
+	for _, tc := range tests {
+		queries := make(chan string)
+		go func() {
+			defer close(queries)
+			for _, q := range []string{"1.1.1.1", "AS15169", "banana", "9.9.9.9", "8.8.8.0/24"} {
+				queries <- q
+			}
+		}()
+
+		var buf bytes.Buffer
+		w, err := newWriter(&buf, tc.format, tc.quiet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status := exitOK
+		emit := func(r record) error {
+			if r.status > status {
+				status = r.status
+			}
+			return w.write(r)
+		}
+		if err := run(c, queries, 3, emit); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s: Got:\n%s\nWant:\n%s", tc.format, got, tc.want)
+		}
+		if status != exitInvalid {
+			t.Errorf("%s: Got status %d, Want: %d", tc.format, status, exitInvalid)
+		}
+	}
+}
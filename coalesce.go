@@ -0,0 +1,81 @@
+package bgpstuff
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// coalescer collapses identical requests into one upstream call. Callers
+// arriving while a request is in flight, or within window of it finishing,
+// share its answer. Errors are never shared past the in-flight call, and a
+// call given up by its own caller is not shared at all.
+type coalescer struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	resp *response
+	err  error
+	// abandoned is set if the caller making the request gave up on it,
+	// so its error says nothing about the API.
+	abandoned bool
+}
+
+func newCoalescer(window time.Duration) *coalescer {
+	return &coalescer{
+		window: window,
+		calls:  make(map[string]*coalescedCall),
+	}
+}
+
+// do returns the shared answer for key, calling fetch if there isn't one.
+// ctx is the caller's own context: a caller waiting on another's request
+// stops waiting when it is done, and makes the request itself if the other
+// caller gave up on it.
+func (co *coalescer) do(ctx context.Context, key string, fetch func() (*response, error)) (*response, error) {
+	for {
+		co.mu.Lock()
+		call, ok := co.calls[key]
+		if !ok {
+			break
+		}
+		co.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !call.abandoned {
+			return call.resp, call.err
+		}
+		co.forget(key, call)
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	co.calls[key] = call
+	co.mu.Unlock()
+
+	call.resp, call.err = fetch()
+	call.abandoned = call.err != nil && ctx.Err() != nil
+	close(call.done)
+
+	if call.err != nil {
+		co.forget(key, call)
+	} else {
+		time.AfterFunc(co.window, func() { co.forget(key, call) })
+	}
+
+	return call.resp, call.err
+}
+
+func (co *coalescer) forget(key string, call *coalescedCall) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	if co.calls[key] == call {
+		delete(co.calls, key)
+	}
+}
@@ -0,0 +1,90 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fakeAPI(w, r)
+	}, WithCoalesce(time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if origin, err := c.GetOrigin("1.1.1.1"); err != nil || origin != 13335 {
+				t.Errorf("Got: %d %v, Want: 13335", origin, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Got %d requests, Want 1", got)
+	}
+}
+
+func TestCoalesceContexts(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{})
+	var requests int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		fakeAPI(w, r)
+	}, WithLimiter(&countingLimiter{}), WithCoalesce(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := c.GetOrigin("1.1.1.1", WithContext(ctx))
+		leader <- err
+	}()
+	<-started
+
+	// A waiter gives up at its own deadline, not the leader's.
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := c.GetOrigin("1.1.1.1", WithContext(short)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Got: %v, Want: %v", err, context.DeadlineExceeded)
+	}
+
+	// A waiter whose context is live makes its own request when the leader
+	// is cancelled, rather than getting the leader's error.
+	waiter := make(chan error)
+	go func() {
+		origin, err := c.GetOrigin("1.1.1.1")
+		if err == nil && origin != 13335 {
+			err = fmt.Errorf("got origin %d", origin)
+		}
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("Got leader error: %v, Want: %v", err, context.Canceled)
+	}
+	if err := <-waiter; err != nil {
+		t.Errorf("Got waiter error: %v, Want none", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Got %d requests, Want 2", got)
+	}
+}
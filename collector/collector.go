@@ -0,0 +1,149 @@
+// Package collector records the size of the global routing table, and
+// optionally the number of ROA invalids, at a fixed interval, and answers
+// questions about how they changed. It is enough for trend reports, such
+// as how fast the IPv6 table is growing, without a separate scraping and
+// time series stack.
+package collector
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+const defaultInterval = time.Hour
+
+// ErrNotEnoughSamples is returned by GrowthRate if the window holds fewer
+// than two samples.
+var ErrNotEnoughSamples = errors.New("need at least two samples")
+
+// Sample is the state of the table at one time.
+type Sample struct {
+	Time time.Time `json:"time"`
+	IPv4 int       `json:"ipv4"`
+	IPv6 int       `json:"ipv6"`
+	// InvalidASNs and InvalidPrefixes are only recorded if the collector
+	// was created WithInvalids.
+	InvalidASNs     int `json:"invalid_asns,omitempty"`
+	InvalidPrefixes int `json:"invalid_prefixes,omitempty"`
+}
+
+// Store keeps samples. MemoryStore and FileStore are provided.
+type Store interface {
+	// Append adds a sample, which is newer than any already stored.
+	Append(Sample) error
+	// Samples returns the samples taken at or after since, oldest first.
+	Samples(since time.Time) ([]Sample, error)
+}
+
+// Option configures a Collector.
+type Option func(*Collector)
+
+// WithInterval sets how often Run takes a sample. The default is an hour.
+func WithInterval(d time.Duration) Option {
+	return func(col *Collector) {
+		col.interval = d
+	}
+}
+
+// WithInvalids has each sample count the ROA invalids as well. This
+// downloads the invalids table each time, replacing the client's copy, so
+// the interval should be long.
+func WithInvalids() Option {
+	return func(col *Collector) {
+		col.invalids = true
+	}
+}
+
+// Collector takes samples and keeps them in a Store.
+type Collector struct {
+	client   *bgpstuff.Client
+	store    Store
+	interval time.Duration
+	invalids bool
+}
+
+// New returns a Collector sampling with c and keeping the samples in store.
+func New(c *bgpstuff.Client, store Store, opts ...Option) *Collector {
+	col := &Collector{client: c, store: store, interval: defaultInterval}
+	for _, opt := range opts {
+		opt(col)
+	}
+	return col
+}
+
+// Collect takes a sample now and stores it.
+func (col *Collector) Collect(ctx context.Context) (Sample, error) {
+	s := Sample{Time: time.Now()}
+	var err error
+	s.IPv4, s.IPv6, err = col.client.GetTotals(bgpstuff.WithContext(ctx))
+	if err != nil {
+		return Sample{}, err
+	}
+	if col.invalids {
+		if err := col.client.GetInvalids(bgpstuff.WithContext(ctx)); err != nil {
+			return Sample{}, err
+		}
+		for _, count := range col.client.TopInvalidASNs(0) {
+			s.InvalidASNs++
+			s.InvalidPrefixes += count.Count
+		}
+	}
+	if err := col.store.Append(s); err != nil {
+		return Sample{}, err
+	}
+	return s, nil
+}
+
+// Run takes a sample straight away and then at every interval until ctx is
+// done. Samples that fail are skipped, to be taken again at the next
+// interval; onError, if not nil, is called with why.
+func (col *Collector) Run(ctx context.Context, onError func(error)) error {
+	ticker := time.NewTicker(col.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := col.Collect(ctx); err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Growth is how the table changed between two samples.
+type Growth struct {
+	From, To Sample
+	// The changes are per day, and may be negative.
+	IPv4PerDay            float64
+	IPv6PerDay            float64
+	InvalidPrefixesPerDay float64
+}
+
+// GrowthRate returns how the table changed over the last window, from the
+// first sample in it to the last.
+func (col *Collector) GrowthRate(window time.Duration) (Growth, error) {
+	samples, err := col.store.Samples(time.Now().Add(-window))
+	if err != nil {
+		return Growth{}, err
+	}
+	if len(samples) < 2 {
+		return Growth{}, ErrNotEnoughSamples
+	}
+	from, to := samples[0], samples[len(samples)-1]
+	days := to.Time.Sub(from.Time).Hours() / 24
+	if days <= 0 {
+		return Growth{}, ErrNotEnoughSamples
+	}
+	return Growth{
+		From:                  from,
+		To:                    to,
+		IPv4PerDay:            float64(to.IPv4-from.IPv4) / days,
+		IPv6PerDay:            float64(to.IPv6-from.IPv6) / days,
+		InvalidPrefixesPerDay: float64(to.InvalidPrefixes-from.InvalidPrefixes) / days,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package collector_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/bgpstufftest"
+	"github.com/mellowdrifter/go-bgpstuff.net/collector"
+)
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+	fixture := bgpstufftest.DefaultFixture()
+	srv := bgpstufftest.StartLocalServer(t, fixture)
+	store := collector.NewFileStore(filepath.Join(t.TempDir(), "totals.jsonl"))
+	col := collector.New(srv.Client(), store, collector.WithInvalids())
+
+	got, err := col.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IPv4+got.IPv6 != len(fixture.Routes) {
+		t.Errorf("Got %d IPv4 and %d IPv6, Want %d in total", got.IPv4, got.IPv6, len(fixture.Routes))
+	}
+	if got.InvalidASNs != 1 || got.InvalidPrefixes != 1 {
+		t.Errorf("Got %d invalid ASNs and %d prefixes, Want 1 and 1", got.InvalidASNs, got.InvalidPrefixes)
+	}
+	if _, err := col.GrowthRate(time.Hour); !errors.Is(err, collector.ErrNotEnoughSamples) {
+		t.Errorf("Got error %v with one sample, Want ErrNotEnoughSamples", err)
+	}
+
+	stored, err := store.Samples(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || !stored[0].Time.Equal(got.Time) || stored[0].IPv4 != got.IPv4 {
+		t.Errorf("Got stored: %+v, Want [%+v]", stored, got)
+	}
+}
+
+func TestGrowthRate(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	store := collector.NewMemoryStore()
+	for _, s := range []collector.Sample{
+		{Time: now.Add(-30 * 24 * time.Hour), IPv4: 1, IPv6: 1},
+		{Time: now.Add(-2 * 24 * time.Hour), IPv4: 900000, IPv6: 200000, InvalidPrefixes: 5000},
+		{Time: now.Add(-24 * time.Hour), IPv4: 900100, IPv6: 200050, InvalidPrefixes: 5010},
+		{Time: now, IPv4: 900200, IPv6: 200100, InvalidPrefixes: 4990},
+	} {
+		if err := store.Append(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	col := collector.New(nil, store)
+
+	got, err := col.GrowthRate(72 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IPv4PerDay != 100 || got.IPv6PerDay != 50 || got.InvalidPrefixesPerDay != -5 {
+		t.Errorf("Got: %+v, Want 100 IPv4, 50 IPv6 and -5 invalids a day", got)
+	}
+	if got.From.IPv4 != 900000 || got.To.IPv4 != 900200 {
+		t.Errorf("Got from %+v to %+v", got.From, got.To)
+	}
+	if _, err := col.GrowthRate(time.Hour); !errors.Is(err, collector.ErrNotEnoughSamples) {
+		t.Errorf("Got error %v, Want ErrNotEnoughSamples", err)
+	}
+}
@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps samples in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	samples []Sample
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Append adds s.
+func (m *MemoryStore) Append(s Sample) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, s)
+	return nil
+}
+
+// Samples returns the samples taken at or after since.
+func (m *MemoryStore) Samples(since time.Time) ([]Sample, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := sort.Search(len(m.samples), func(i int) bool { return !m.samples[i].Time.Before(since) })
+	return append([]Sample(nil), m.samples[i:]...), nil
+}
+
+// FileStore keeps samples in a file, one JSON object per line, so they
+// outlive the program and can be read by other tools. It is safe for
+// concurrent use, but not by more than one process at once.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore returns a FileStore keeping samples in path, which is
+// created when the first sample is added.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Append adds s to the end of the file.
+func (f *FileStore) Append(s Sample) error {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Samples reads the samples taken at or after since from the file.
+func (f *FileStore) Samples(since time.Time) ([]Sample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var s Sample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			return nil, fmt.Errorf("samples %s: %w", f.path, err)
+		}
+		if !s.Time.Before(since) {
+			samples = append(samples, s)
+		}
+	}
+	return samples, scanner.Err()
+}
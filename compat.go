@@ -0,0 +1,205 @@
+package bgpstuff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientVersion returns the version of this package.
+func ClientVersion() string {
+	return version
+}
+
+// SupportedAPIVersions returns the major versions of the bgpstuff.net API
+// response schema this package can decode.
+func SupportedAPIVersions() []int {
+	return []int{1}
+}
+
+// ErrIncompatibleAPI is returned by VerifyCompatibility when the server's
+// responses no longer have the shape this package decodes.
+type ErrIncompatibleAPI struct {
+	// Version is the server's version, set if its major version is not one
+	// of SupportedAPIVersions.
+	Version string
+	// Missing is the response field that could not be found.
+	Missing string
+}
+
+func (e ErrIncompatibleAPI) Error() string {
+	if e.Version != "" {
+		return fmt.Sprintf("incompatible API: server version %s, supported major versions %v", e.Version, SupportedAPIVersions())
+	}
+	return fmt.Sprintf("incompatible API: response has no %s field", e.Missing)
+}
+
+// verifyIP is the address VerifyCompatibility looks up, which is always
+// routed.
+const verifyIP = "1.1.1.1"
+
+// VerifyCompatibility checks that the server still answers in the schema
+// this package understands. Without it, a schema change on the server shows
+// up as lookups quietly returning zero values. It checks that:
+//
+//   - the major version of the server, if it has the /version handler, is
+//     one of SupportedAPIVersions;
+//   - /totals answers with its Ipv4 and Ipv6 counts;
+//   - /route and /origin answer with their Route and Origin fields.
+//
+// Other handlers and fields are not checked. It is meant to be called once
+// at startup and costs four requests.
+func (c *Client) VerifyCompatibility(ctx context.Context) error {
+	version, err := c.GetServerVersion(WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if version != "" && !supportedVersion(version) {
+		return ErrIncompatibleAPI{Version: version}
+	}
+
+	checks := []struct {
+		urls   []string
+		fields []string
+	}{
+		{urls: []string{"totals"}, fields: []string{"Totals.Ipv4", "Totals.Ipv6"}},
+		{urls: []string{"route", verifyIP}, fields: []string{"Route"}},
+		{urls: []string{"origin", verifyIP}, fields: []string{"Origin"}},
+	}
+	for _, check := range checks {
+		if err := c.checkFields(ctx, check.urls, check.fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// supportedVersion reports whether the major number of version, such as
+// "v1.4.2", is one of SupportedAPIVersions.
+func supportedVersion(version string) bool {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return false
+	}
+	for _, v := range SupportedAPIVersions() {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFields asks the query made up of urls and checks the response has
+// each of fields, given as dotted paths below Response.
+func (c *Client) checkFields(ctx context.Context, urls []string, fields []string) error {
+	var raw struct {
+		Response map[string]json.RawMessage
+	}
+	decode := func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&raw)
+	}
+	if err := c.fetch(&callOptions{ctx: ctx}, urls[0], c.getURI(urls), decode); err != nil {
+		return lookupError(urls[0], strings.Join(urls[1:], "/"), err)
+	}
+	if raw.Response == nil {
+		return ErrIncompatibleAPI{Missing: "Response"}
+	}
+
+	for _, field := range fields {
+		obj := raw.Response
+		path := "Response"
+		names := strings.Split(field, ".")
+		for i, name := range names {
+			path += "." + name
+			value, ok := obj[name]
+			if !ok {
+				return ErrIncompatibleAPI{Missing: path}
+			}
+			if i == len(names)-1 {
+				break
+			}
+			obj = nil
+			if err := json.Unmarshal(value, &obj); err != nil || obj == nil {
+				return ErrIncompatibleAPI{Missing: path}
+			}
+		}
+	}
+	return nil
+}
+
+// serverInfo is the body of a /version or /uptime answer. bgpstuff.netv2
+// has no model for these handlers, so they are kept out of the api package
+// and decoded here.
+type serverInfo struct {
+	Action  string
+	Version string
+	Uptime  string
+}
+
+// getServerInfo asks endpoint for serverInfo, which is empty if the server
+// has no such handler. The answers describe the server at the time of
+// asking, so they are never cached.
+func (c *Client) getServerInfo(opts []CallOption, endpoint string) (serverInfo, error) {
+	var raw struct {
+		Response serverInfo
+	}
+	decode := func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&raw)
+	}
+	call := newCallOptions(opts)
+	err := c.fetch(call, endpoint, c.getURI([]string{endpoint}), decode)
+	if err == nil {
+		err = checkAction(endpoint, raw.Response.Action)
+	}
+	c.metrics.request(endpoint, call.requestTime(), err)
+	var status *statusError
+	if errors.As(err, &status) && status.code == http.StatusNotFound {
+		// Servers from before the handler was added.
+		return serverInfo{}, nil
+	}
+	if err != nil {
+		return serverInfo{}, lookupError(endpoint, "", err)
+	}
+	return raw.Response, nil
+}
+
+// GetServerVersion uses the /version handler and returns the build of the
+// server answering, so data can be traced back to the backend that served
+// it. Servers from before the handler was added answer 404 Not Found, and
+// for them it returns "".
+func (c *Client) GetServerVersion(opts ...CallOption) (string, error) {
+	info, err := c.getServerInfo(opts, "version")
+	if err != nil {
+		return "", err
+	}
+	return info.Version, nil
+}
+
+// GetServerUptime uses the /uptime handler and returns how long the server
+// has been running. Servers from before the handler was added answer 404
+// Not Found, and for them it returns 0. The server may give the uptime as a
+// duration or in seconds.
+func (c *Client) GetServerUptime(opts ...CallOption) (time.Duration, error) {
+	info, err := c.getServerInfo(opts, "uptime")
+	if err != nil {
+		return 0, err
+	}
+	if info.Uptime == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.ParseInt(info.Uptime, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(info.Uptime)
+	if err != nil {
+		return 0, lookupError("uptime", "", err)
+	}
+	return d, nil
+}
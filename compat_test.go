@@ -0,0 +1,118 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifyCompatibility(t *testing.T) {
+	t.Parallel()
+	const totals = `{"Response":{"Totals":{"Ipv4":900000,"Ipv6":150000}}}`
+	tests := []struct {
+		name string
+		// responses replace fakeAPI's answer for their path.
+		responses   map[string]string
+		wantVersion string
+		wantMissing string
+	}{
+		{
+			name:      "compatible",
+			responses: map[string]string{"/totals": totals},
+		},
+		{
+			name: "supported version",
+			responses: map[string]string{
+				"/version": `{"Response":{"Action":"version","Version":"v1.4.2"}}`,
+				"/totals":  totals,
+			},
+		},
+		{
+			name: "newer major version",
+			responses: map[string]string{
+				"/version": `{"Response":{"Action":"version","Version":"v2.0.0"}}`,
+				"/totals":  totals,
+			},
+			wantVersion: "v2.0.0",
+		},
+		{
+			name:        "renamed envelope",
+			responses:   map[string]string{"/totals": `{"Data":{"Totals":{"Ipv4":900000,"Ipv6":150000}}}`},
+			wantMissing: "Response",
+		},
+		{
+			name:        "renamed totals field",
+			responses:   map[string]string{"/totals": `{"Response":{"Totals":{"IPv4":900000,"IPv6":150000}}}`},
+			wantMissing: "Response.Totals.Ipv4",
+		},
+		{
+			name: "renamed origin field",
+			responses: map[string]string{
+				"/totals":         totals,
+				"/origin/1.1.1.1": `{"Response":{"Action":"origin","OriginASN":"13335"}}`,
+			},
+			wantMissing: "Response.Origin",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if body, ok := tc.responses[r.URL.Path]; ok {
+					fmt.Fprint(w, body)
+					return
+				}
+				if r.URL.Path == "/version" {
+					http.NotFound(w, r)
+					return
+				}
+				fakeAPI(w, r)
+			}, WithLimiter(&countingLimiter{}))
+			err := c.VerifyCompatibility(context.Background())
+			var incompatible ErrIncompatibleAPI
+			if tc.wantMissing == "" && tc.wantVersion == "" {
+				if err != nil {
+					t.Errorf("No error expected, but got error: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &incompatible) || incompatible.Missing != tc.wantMissing || incompatible.Version != tc.wantVersion {
+				t.Errorf("Got: %v, Want version %q missing %q", err, tc.wantVersion, tc.wantMissing)
+			}
+		})
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	t.Parallel()
+	uptime := "72h3m0s"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/version":
+			fmt.Fprint(w, `{"Response":{"Action":"version","Version":"v2.3.1","Exists":true}}`)
+		case "/uptime":
+			fmt.Fprintf(w, `{"Response":{"Action":"uptime","Uptime":%q,"Exists":true}}`, uptime)
+		}
+	})
+	if got, err := c.GetServerVersion(); err != nil || got != "v2.3.1" {
+		t.Errorf("GetServerVersion() = %q, %v, Want: v2.3.1", got, err)
+	}
+
+	// Servers without the handlers.
+	old := newTestClient(t, http.NotFound)
+	if got, err := old.GetServerVersion(); err != nil || got != "" {
+		t.Errorf("GetServerVersion() = %q, %v, Want: \"\"", got, err)
+	}
+	if got, err := old.GetServerUptime(); err != nil || got != 0 {
+		t.Errorf("GetServerUptime() = %v, %v, Want: 0", got, err)
+	}
+	if got, err := c.GetServerUptime(); err != nil || got != 72*time.Hour+3*time.Minute {
+		t.Errorf("GetServerUptime() = %v, %v, Want: 72h3m0s", got, err)
+	}
+	uptime = "60"
+	if got, err := c.GetServerUptime(); err != nil || got != time.Minute {
+		t.Errorf("GetServerUptime() = %v, %v, Want: 1m0s", got, err)
+	}
+}
@@ -0,0 +1,337 @@
+// Package enrich annotates flow records, such as those from NetFlow, IPFIX
+// or sFlow collectors, with the route, origin AS and AS name of each end.
+//
+// A pipeline sees far more flows than it could ever make API requests for,
+// so Annotate never waits on the network. It answers from a local longest
+// prefix match table and the client's AS name table, and queues addresses it
+// has no route for to be looked up in the background by Run. The first
+// flows to a new network go out unannotated; the ones after them don't.
+//
+// The table is bounded, see WithMaxRoutes, and routes not seen for twice
+// the refresh interval are dropped, so scans across unrouted space don't
+// grow it without limit.
+package enrich
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+const (
+	defaultRefresh   = time.Hour
+	defaultWorkers   = 4
+	defaultQueueSize = 4096
+	defaultMaxRoutes = 100000
+)
+
+// Endpoint is what is known about one end of a flow. Prefix is invalid and
+// Origin is 0 if the address has no route, or has not been looked up yet.
+type Endpoint struct {
+	Addr   netip.Addr
+	Prefix netip.Prefix
+	Origin bgpstuff.ASN
+	ASName string
+}
+
+// FlowAnnotation is the annotation of a single flow.
+type FlowAnnotation struct {
+	Src Endpoint
+	Dst Endpoint
+}
+
+// Option configures an Enricher.
+type Option func(*Enricher)
+
+// WithRefresh sets how long a looked up route is used before it is looked
+// up again, and how often Run reloads the AS name table. Routes that have
+// not been looked up again for twice this long, as no flow has used them,
+// are dropped. The default is an hour.
+func WithRefresh(d time.Duration) Option {
+	return func(e *Enricher) {
+		e.refresh = d
+	}
+}
+
+// WithWorkers sets how many lookups Run makes at once. The client's rate
+// limit still applies across all of them. The default is 4.
+func WithWorkers(n int) Option {
+	return func(e *Enricher) {
+		e.workers = n
+	}
+}
+
+// WithQueueSize sets how many addresses may wait to be looked up. Misses
+// beyond that are dropped, and queued again the next time they are seen.
+// The default is 4096.
+func WithQueueSize(n int) Option {
+	return func(e *Enricher) {
+		e.queueSize = n
+	}
+}
+
+// WithMaxRoutes bounds the table to n routes, counting the networks
+// remembered as having no route. Once full, the route looked up longest
+// ago is dropped to make room. The default is 100000.
+func WithMaxRoutes(n int) Option {
+	return func(e *Enricher) {
+		e.maxRoutes = n
+	}
+}
+
+// Enricher annotates flows. It is safe for concurrent use.
+type Enricher struct {
+	client    *bgpstuff.Client
+	refresh   time.Duration
+	workers   int
+	queueSize int
+	maxRoutes int
+
+	mu sync.RWMutex
+	// routes holds each looked up prefix, keyed by length. lengths lists
+	// the keys present, longest first, so a lookup only probes those.
+	routes  map[int]map[netip.Prefix]route
+	lengths []int
+	// order lists the prefixes in routes, most recently looked up first,
+	// and elems finds each in it.
+	order *list.List
+	elems map[netip.Prefix]*list.Element
+	// pending is the set of networks queued or being looked up.
+	pending map[netip.Prefix]bool
+
+	queue chan netip.Addr
+}
+
+type route struct {
+	prefix  netip.Prefix
+	origin  bgpstuff.ASN
+	fetched time.Time
+}
+
+// New returns an Enricher making its lookups with c. Call Run to have
+// misses looked up; without it only routes added with Resolve are known.
+func New(c *bgpstuff.Client, opts ...Option) *Enricher {
+	e := &Enricher{
+		client:    c,
+		refresh:   defaultRefresh,
+		workers:   defaultWorkers,
+		queueSize: defaultQueueSize,
+		maxRoutes: defaultMaxRoutes,
+		routes:    make(map[int]map[netip.Prefix]route),
+		order:     list.New(),
+		elems:     make(map[netip.Prefix]*list.Element),
+		pending:   make(map[netip.Prefix]bool),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.workers < 1 {
+		e.workers = 1
+	}
+	if e.queueSize < 1 {
+		e.queueSize = 1
+	}
+	if e.maxRoutes < 1 {
+		e.maxRoutes = 1
+	}
+	e.queue = make(chan netip.Addr, e.queueSize)
+	return e
+}
+
+// Annotate returns what is known about src and dst. It does not block on
+// the API: addresses with no route yet, or whose route is older than the
+// refresh interval, are queued for Run to look up.
+func (e *Enricher) Annotate(src, dst netip.Addr) FlowAnnotation {
+	return FlowAnnotation{Src: e.endpoint(src), Dst: e.endpoint(dst)}
+}
+
+func (e *Enricher) endpoint(addr netip.Addr) Endpoint {
+	addr = addr.Unmap()
+	ep := Endpoint{Addr: addr}
+	r, ok := e.lookup(addr)
+	if !ok || time.Since(r.fetched) > e.refresh {
+		e.enqueue(addr)
+	}
+	if !ok || r.origin == 0 {
+		return ep
+	}
+	ep.Prefix, ep.Origin = r.prefix, r.origin
+	if info, ok := e.client.GetASInfo(int(r.origin)); ok {
+		ep.ASName = info.ASName
+	}
+	return ep
+}
+
+// lookup returns the route for the longest prefix containing addr.
+func (e *Enricher) lookup(addr netip.Addr) (route, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, bits := range e.lengths {
+		if bits > addr.BitLen() {
+			continue
+		}
+		p, _ := addr.Prefix(bits)
+		if r, ok := e.routes[bits][p]; ok {
+			return r, true
+		}
+	}
+	return route{}, false
+}
+
+// enqueue queues addr to be looked up, unless another address in the same
+// network already is. Nothing more specific than a /24 or /48 is routed on
+// the internet, so that is the network used.
+func (e *Enricher) enqueue(addr netip.Addr) {
+	if !addr.IsValid() {
+		return
+	}
+	key := network(addr)
+	e.mu.RLock()
+	queued := e.pending[key]
+	e.mu.RUnlock()
+	if queued {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.pending[key] {
+		return
+	}
+	select {
+	case e.queue <- addr:
+		e.pending[key] = true
+	default:
+	}
+}
+
+func network(addr netip.Addr) netip.Prefix {
+	bits := 24
+	if addr.Is6() {
+		bits = 48
+	}
+	p, _ := addr.Prefix(bits)
+	return p
+}
+
+// Resolve looks up the route covering addr and adds it to the table,
+// waiting for the request. Run calls it for each queued address; it can
+// also be used to warm the table before flows arrive.
+func (e *Enricher) Resolve(ctx context.Context, addr netip.Addr) error {
+	addr = addr.Unmap()
+	defer func() {
+		e.mu.Lock()
+		delete(e.pending, network(addr))
+		e.mu.Unlock()
+	}()
+
+	res, err := e.client.GetROADetail(addr.String(),
+		bgpstuff.WithContext(ctx), bgpstuff.WithPriority(bgpstuff.PriorityLow))
+	switch {
+	case errors.Is(err, bgpstuff.ErrInvalidIP):
+		// Private and reserved addresses are never routed; remember
+		// that so they aren't queued on every flow.
+		e.add(route{prefix: network(addr), fetched: time.Now()})
+		return nil
+	case err != nil:
+		return err
+	case res == nil || !res.Prefix.IsValid():
+		e.add(route{prefix: network(addr), fetched: time.Now()})
+		return nil
+	}
+	e.add(route{prefix: res.Prefix.Masked(), origin: res.Origin, fetched: time.Now()})
+	return nil
+}
+
+func (e *Enricher) add(r route) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	bits := r.prefix.Bits()
+	if e.routes[bits] == nil {
+		e.routes[bits] = make(map[netip.Prefix]route)
+		e.lengths = append(e.lengths, bits)
+		for i := len(e.lengths) - 1; i > 0 && e.lengths[i] > e.lengths[i-1]; i-- {
+			e.lengths[i], e.lengths[i-1] = e.lengths[i-1], e.lengths[i]
+		}
+	}
+	e.routes[bits][r.prefix] = r
+	if el, ok := e.elems[r.prefix]; ok {
+		e.order.MoveToFront(el)
+	} else {
+		e.elems[r.prefix] = e.order.PushFront(r.prefix)
+	}
+
+	// Drop routes past the bound, and those no flow has needed looked up
+	// again for two refresh intervals. Both are at the back.
+	for e.order.Len() > 0 {
+		oldest := e.order.Back().Value.(netip.Prefix)
+		expired := e.refresh > 0 && time.Since(e.routes[oldest.Bits()][oldest].fetched) > 2*e.refresh
+		if e.order.Len() <= e.maxRoutes && !expired {
+			break
+		}
+		e.remove(oldest)
+	}
+}
+
+// remove drops the route for p. e.mu must be held.
+func (e *Enricher) remove(p netip.Prefix) {
+	e.order.Remove(e.elems[p])
+	delete(e.elems, p)
+	bits := p.Bits()
+	delete(e.routes[bits], p)
+	if len(e.routes[bits]) > 0 {
+		return
+	}
+	delete(e.routes, bits)
+	for i, n := range e.lengths {
+		if n == bits {
+			e.lengths = append(e.lengths[:i], e.lengths[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of routes in the table.
+func (e *Enricher) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.order.Len()
+}
+
+// Run looks up queued addresses and reloads the AS name table at the
+// refresh interval until ctx is done, then returns ctx's error. Lookups
+// that fail are dropped, and queued again when the address is next seen.
+func (e *Enricher) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < e.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case addr := <-e.queue:
+					e.Resolve(ctx, addr)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	e.client.GetASNames(bgpstuff.WithContext(ctx))
+	ticker := time.NewTicker(e.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.client.GetASNames(bgpstuff.WithContext(ctx))
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package enrich_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/bgpstufftest"
+	"github.com/mellowdrifter/go-bgpstuff.net/enrich"
+)
+
+func TestAnnotate(t *testing.T) {
+	t.Parallel()
+	srv := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture())
+	c := srv.Client()
+	if err := c.GetASNames(); err != nil {
+		t.Fatal(err)
+	}
+	e := enrich.New(c)
+
+	src, dst := netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("2606:4700::1111")
+	got := e.Annotate(src, dst)
+	if got.Src.Origin != 0 || got.Src.Prefix.IsValid() {
+		t.Errorf("Got: %+v before lookup, Want nothing", got.Src)
+	}
+
+	for _, addr := range []netip.Addr{src, dst, netip.MustParseAddr("203.0.113.1")} {
+		if err := e.Resolve(context.Background(), addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got = e.Annotate(src, dst)
+	want := enrich.Endpoint{Addr: src, Prefix: netip.MustParsePrefix("1.1.1.0/24"), Origin: 13335, ASName: "CLOUDFLARENET"}
+	if got.Src != want {
+		t.Errorf("Got src: %+v, Want: %+v", got.Src, want)
+	}
+	if got.Dst.Prefix != netip.MustParsePrefix("2606:4700::/32") || got.Dst.Origin != 13335 {
+		t.Errorf("Got dst: %+v", got.Dst)
+	}
+	// An address in the same /24 is answered from the table.
+	if got := e.Annotate(netip.MustParseAddr("1.1.1.200"), src); got.Src.Origin != 13335 {
+		t.Errorf("Got: %+v, Want AS13335", got.Src)
+	}
+	// Unrouted and private addresses are remembered as having no route.
+	got = e.Annotate(netip.MustParseAddr("203.0.113.1"), netip.MustParseAddr("::ffff:1.1.1.1"))
+	if got.Src.Origin != 0 || got.Dst.Origin != 13335 {
+		t.Errorf("Got: %+v", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	srv := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture())
+	e := enrich.New(srv.Client(), enrich.WithWorkers(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- e.Run(ctx) }()
+
+	addr := netip.MustParseAddr("8.8.8.8")
+	deadline := time.Now().Add(5 * time.Second)
+	for e.Annotate(addr, addr).Src.ASName != "GOOGLE" {
+		if time.Now().After(deadline) {
+			t.Fatal("8.8.8.8 was not looked up by Run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Got: %v, Want: %v", err, context.Canceled)
+	}
+}
+
+func TestTableBounds(t *testing.T) {
+	t.Parallel()
+	srv := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture())
+	resolve := func(e *enrich.Enricher, addrs ...string) {
+		for _, addr := range addrs {
+			if err := e.Resolve(context.Background(), netip.MustParseAddr(addr)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tests := []struct {
+		name string
+		opts []enrich.Option
+		// wait is how long to wait before the last lookup.
+		wait time.Duration
+		want int
+	}{
+		{name: "unbounded", want: 3},
+		{name: "size", opts: []enrich.Option{enrich.WithMaxRoutes(2)}, want: 2},
+		{name: "expired", opts: []enrich.Option{enrich.WithRefresh(time.Millisecond)}, wait: 10 * time.Millisecond, want: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := enrich.New(srv.Client(), tc.opts...)
+			// Scanner traffic across unrouted space.
+			resolve(e, "203.0.113.1", "198.51.100.1")
+			time.Sleep(tc.wait)
+			resolve(e, "1.1.1.1")
+			if got := e.Len(); got != tc.want {
+				t.Errorf("Got %d routes, Want: %d", got, tc.want)
+			}
+			// The newest route always stays.
+			if got := e.Annotate(netip.MustParseAddr("1.1.1.1"), netip.Addr{}); got.Src.Origin != 13335 {
+				t.Errorf("Got: %+v, Want AS13335", got.Src)
+			}
+		})
+	}
+}
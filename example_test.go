@@ -0,0 +1,103 @@
+package bgpstuff_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+func ExampleClient_GetRoute() {
+	c := bgpstuff.NewBGPClient(false)
+	route, err := c.GetRoute("1.1.1.1")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if route == nil {
+		fmt.Println("no route")
+		return
+	}
+	fmt.Println(route)
+}
+
+func ExampleClient_GetRouteResult() {
+	c := bgpstuff.NewBGPClient(false)
+	res, err := c.GetRouteResult("1.1.1.1")
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case !res.Exists:
+		fmt.Println("no route")
+	case res.Default:
+		fmt.Println("default route only")
+	default:
+		fmt.Println(res.Prefix)
+	}
+}
+
+func ExampleClient_GetOrigin() {
+	c := bgpstuff.NewBGPClient(false)
+	origin, err := c.GetOrigin("8.8.8.8")
+	if errors.Is(err, bgpstuff.ErrInvalidIP) {
+		log.Fatal("not a public IP")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	name, err := c.GetASName(origin)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("AS%d %s\n", origin, name)
+}
+
+func ExampleClient_GetASNames() {
+	c := bgpstuff.NewBGPClient(false)
+	// Loading the whole table once saves a request per name afterwards.
+	if err := c.GetASNames(); err != nil {
+		log.Fatal(err)
+	}
+	info, ok := c.GetASInfo(3356)
+	if ok {
+		fmt.Println(info.ASName, info.ASLocale)
+	}
+}
+
+func ExampleClient_GetInvalid() {
+	c := bgpstuff.NewBGPClient(false)
+	if err := c.GetInvalids(); err != nil {
+		log.Fatal(err)
+	}
+	prefixes, err := c.GetInvalid(13335)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range prefixes {
+		fmt.Println(p)
+	}
+}
+
+func ExampleClient_Report() {
+	c := bgpstuff.NewBGPClient(false)
+	report, err := c.Report("1.1.1.1")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(report)
+}
+
+func ExampleClassify() {
+	fmt.Println(bgpstuff.Classify("10.1.1.1"))
+	fmt.Println(bgpstuff.Classify("1.1.1.1"))
+	// Output:
+	// Private
+	// Public
+}
+
+func ExampleDetectPrepending() {
+	asn, count := bgpstuff.DetectPrepending([]int{174, 13335, 13335, 13335})
+	fmt.Printf("AS%d prepended %d times\n", asn, count)
+	// Output: AS13335 prepended 3 times
+}
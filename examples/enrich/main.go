@@ -0,0 +1,61 @@
+// Command enrich reads IP addresses from stdin, one per line, and writes
+// each one out with its route, origin ASN and AS name as CSV.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+func main() {
+	c := bgpstuff.NewBGPClient(false)
+	// Load every AS name up front so each line only costs route and
+	// origin lookups.
+	if err := c.GetASNames(); err != nil {
+		log.Fatal(err)
+	}
+
+	out := csv.NewWriter(os.Stdout)
+	defer out.Flush()
+	if err := out.Write([]string{"ip", "route", "origin", "as_name"}); err != nil {
+		log.Fatal(err)
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		ip := strings.TrimSpace(in.Text())
+		if ip == "" {
+			continue
+		}
+		route, err := c.GetRoute(ip)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		if route == nil {
+			if err := out.Write([]string{ip, "", "", ""}); err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
+		origin, err := c.GetOrigin(ip)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		info, _ := c.GetASInfo(origin)
+		record := []string{ip, route.String(), fmt.Sprint(origin), info.ASName}
+		if err := out.Write(record); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := in.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
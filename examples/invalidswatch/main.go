@@ -0,0 +1,48 @@
+// Command invalidswatch prints ROA invalid prefixes as they appear and
+// disappear from the global table.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c := bgpstuff.NewBGPClient(false, bgpstuff.WithPollInterval(5*time.Minute))
+	events, err := c.Subscribe(ctx, bgpstuff.TopicInvalids)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	first := true
+	for ev := range events {
+		if ev.Err != nil {
+			log.Print(ev.Err)
+			continue
+		}
+		// The first event is the whole table, which is too much to print.
+		if first {
+			log.Printf("watching invalids from %d ASNs", len(ev.Added))
+			first = false
+			continue
+		}
+		for asn, prefixes := range ev.Added {
+			for _, p := range prefixes {
+				log.Printf("new invalid: %s from AS%d", p, asn)
+			}
+		}
+		for asn, prefixes := range ev.Removed {
+			for _, p := range prefixes {
+				log.Printf("no longer invalid: %s from AS%d", p, asn)
+			}
+		}
+	}
+}
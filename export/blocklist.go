@@ -0,0 +1,210 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlocklistOptions sets the format of a plain blocklist. The zero value
+// writes every prefix, one per line, with no comments.
+type BlocklistOptions struct {
+	// Family limits the list to IPv4 (4) or IPv6 (6) prefixes. 0 writes
+	// both.
+	Family int
+	// Comment starts comment lines. It defaults to "#".
+	Comment string
+	// Header is written at the top of the list, each line as a comment.
+	Header string
+	// Origins appends the origin ASNs of each prefix as a comment.
+	Origins bool
+}
+
+// Blocklist returns the prefixes in invalids, keyed by origin ASN as
+// returned by Client.InvalidTable, as a plain list for firewalls and other
+// tools that read one prefix per line. Prefixes are sorted, and only
+// written once even if several origins announce them.
+func Blocklist(invalids map[int][]*net.IPNet, opts BlocklistOptions) (string, error) {
+	if opts.Family != 0 && opts.Family != 4 && opts.Family != 6 {
+		return "", fmt.Errorf("invalid address family %d", opts.Family)
+	}
+	if opts.Comment == "" {
+		opts.Comment = "#"
+	}
+	if strings.ContainsAny(opts.Comment, "\r\n") {
+		return "", errors.New("comment marker contains a newline")
+	}
+
+	var b strings.Builder
+	writeComment(&b, opts.Comment, opts.Header)
+	for _, e := range blockEntries(invalids) {
+		if opts.Family == 4 && !e.prefix.Addr().Is4() || opts.Family == 6 && e.prefix.Addr().Is4() {
+			continue
+		}
+		b.WriteString(e.prefix.String())
+		if opts.Origins {
+			fmt.Fprintf(&b, " %s %s", opts.Comment, e.originList())
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// RPZAction is the policy an RPZ trigger applies.
+type RPZAction string
+
+// Policies for RPZ triggers.
+const (
+	RPZNXDomain RPZAction = "CNAME ."         // answer NXDOMAIN
+	RPZNoData   RPZAction = "CNAME *."        // answer with no records
+	RPZDrop     RPZAction = "CNAME rpz-drop." // send no answer at all
+)
+
+// RPZOptions holds the zone settings of an RPZ file. Empty fields get a
+// default.
+type RPZOptions struct {
+	Action RPZAction     // defaults to RPZNXDomain
+	TTL    time.Duration // defaults to an hour
+	NS     string        // defaults to "localhost."
+	Serial uint32        // defaults to the current Unix time
+}
+
+// RPZ returns a DNS response policy zone with an rpz-ip trigger for each
+// prefix in invalids, keyed by origin ASN as returned by
+// Client.InvalidTable. Resolvers loading it apply the action to any answer
+// pointing into a ROA invalid prefix.
+func RPZ(invalids map[int][]*net.IPNet, opts RPZOptions) (string, error) {
+	if opts.Action == "" {
+		opts.Action = RPZNXDomain
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	if opts.NS == "" {
+		opts.NS = "localhost."
+	}
+	if opts.Serial == 0 {
+		opts.Serial = uint32(time.Now().Unix())
+	}
+	if err := checkName(opts.NS); err != nil {
+		return "", fmt.Errorf("ns: %w", err)
+	}
+	if strings.ContainsAny(string(opts.Action), "\r\n") {
+		return "", errors.New("action contains a newline")
+	}
+
+	ttl := int(opts.TTL / time.Second)
+	var b strings.Builder
+	fmt.Fprintf(&b, "$TTL %d\n", ttl)
+	fmt.Fprintf(&b, "@ SOA %s hostmaster.%s %d %d %d %d %d\n", opts.NS, opts.NS, opts.Serial, ttl, ttl/6, ttl*24, ttl)
+	fmt.Fprintf(&b, "@ NS %s\n", opts.NS)
+	for _, e := range blockEntries(invalids) {
+		fmt.Fprintf(&b, "%s %s ; %s\n", rpzTrigger(e.prefix), opts.Action, e.originList())
+	}
+	return b.String(), nil
+}
+
+// rpzTrigger returns the rpz-ip owner name for p: the prefix length, then
+// the address in reverse order, with IPv6 zeros shortened to "zz" the way
+// "::" shortens them.
+func rpzTrigger(p netip.Prefix) string {
+	labels := []string{strconv.Itoa(p.Bits())}
+	addr := p.Addr()
+	if addr.Is4() {
+		a := addr.As4()
+		for i := len(a) - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(a[i])))
+		}
+		return strings.Join(labels, ".") + ".rpz-ip"
+	}
+
+	a := addr.As16()
+	var groups [8]uint16
+	for i := range groups {
+		groups[i] = uint16(a[2*i])<<8 | uint16(a[2*i+1])
+	}
+	// Find the longest run of two or more zero groups, first one wins.
+	start, length := -1, 1
+	for i := 0; i < len(groups); {
+		j := i
+		for j < len(groups) && groups[j] == 0 {
+			j++
+		}
+		if j-i > length {
+			start, length = i, j-i
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		if start >= 0 && i >= start && i < start+length {
+			if i == start {
+				labels = append(labels, "zz")
+			}
+			continue
+		}
+		labels = append(labels, strconv.FormatUint(uint64(groups[i]), 16))
+	}
+	return strings.Join(labels, ".") + ".rpz-ip"
+}
+
+type blockEntry struct {
+	prefix  netip.Prefix
+	origins []int
+}
+
+func (e blockEntry) originList() string {
+	asns := make([]string, 0, len(e.origins))
+	for _, asn := range e.origins {
+		asns = append(asns, fmt.Sprintf("AS%d", asn))
+	}
+	return strings.Join(asns, " ")
+}
+
+// blockEntries returns the prefixes in invalids with their origins, IPv4
+// first and then in address order.
+func blockEntries(invalids map[int][]*net.IPNet) []blockEntry {
+	byPrefix := make(map[netip.Prefix]*blockEntry)
+	for asn, prefixes := range invalids {
+		for _, ipnet := range prefixes {
+			p, err := netip.ParsePrefix(ipnet.String())
+			if err != nil {
+				continue
+			}
+			p = p.Masked()
+			if byPrefix[p] == nil {
+				byPrefix[p] = &blockEntry{prefix: p}
+			}
+			byPrefix[p].origins = append(byPrefix[p].origins, asn)
+		}
+	}
+	entries := make([]blockEntry, 0, len(byPrefix))
+	for _, e := range byPrefix {
+		sort.Ints(e.origins)
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].prefix, entries[j].prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+	return entries
+}
+
+func writeComment(b *strings.Builder, marker, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(b, "%s %s\n", marker, line)
+	}
+}
@@ -0,0 +1,117 @@
+// Package export turns prefixes, such as those from Client.GetSourced or
+// Client.InvalidTable, into router configuration snippets, DNS policy zones
+// and blocklists.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var errInvalidName = errors.New("name must be non-empty and contain no whitespace or quotes")
+
+func checkName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n\"'") {
+		return errInvalidName
+	}
+	return nil
+}
+
+// split returns the IPv4 and IPv6 prefixes in prefixes, keeping their order.
+func split(prefixes []*net.IPNet) (v4, v6 []*net.IPNet) {
+	for _, p := range prefixes {
+		if p.IP.To4() != nil {
+			v4 = append(v4, p)
+		} else {
+			v6 = append(v6, p)
+		}
+	}
+	return v4, v6
+}
+
+// CiscoPrefixList returns IOS prefix-lists named name permitting exactly
+// prefixes. IPv4 and IPv6 prefixes go in separate ip and ipv6 lists.
+func CiscoPrefixList(name string, prefixes []*net.IPNet) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	v4, v6 := split(prefixes)
+	for i, p := range v4 {
+		fmt.Fprintf(&b, "ip prefix-list %s seq %d permit %s\n", name, (i+1)*5, p)
+	}
+	for i, p := range v6 {
+		fmt.Fprintf(&b, "ipv6 prefix-list %s seq %d permit %s\n", name, (i+1)*5, p)
+	}
+	return b.String(), nil
+}
+
+// JuniperPrefixList returns set commands for a JunOS prefix-list named name
+// containing prefixes.
+func JuniperPrefixList(name string, prefixes []*net.IPNet) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, p := range prefixes {
+		fmt.Fprintf(&b, "set policy-options prefix-list %s %s\n", name, p)
+	}
+	return b.String(), nil
+}
+
+// JunOSPolicy returns set commands for a JunOS policy-statement named name
+// that accepts exactly prefixes and rejects everything else.
+func JunOSPolicy(name string, prefixes []*net.IPNet) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	v4, v6 := split(prefixes)
+	terms := []struct {
+		name     string
+		prefixes []*net.IPNet
+	}{
+		{"ipv4", v4},
+		{"ipv6", v6},
+	}
+	for _, t := range terms {
+		if len(t.prefixes) == 0 {
+			continue
+		}
+		for _, p := range t.prefixes {
+			fmt.Fprintf(&b, "set policy-options policy-statement %s term %s from route-filter %s exact\n", name, t.name, p)
+		}
+		fmt.Fprintf(&b, "set policy-options policy-statement %s term %s then accept\n", name, t.name)
+	}
+	fmt.Fprintf(&b, "set policy-options policy-statement %s then reject\n", name)
+	return b.String(), nil
+}
+
+// BIRDFilter returns a BIRD 2 filter named name that accepts exactly
+// prefixes and rejects everything else.
+func BIRDFilter(name string, prefixes []*net.IPNet) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	v4, v6 := split(prefixes)
+	fmt.Fprintf(&b, "filter %s {\n", name)
+	if len(v4) > 0 {
+		fmt.Fprintf(&b, "\tif net.type = NET_IP4 then {\n\t\tif net ~ [ %s ] then accept;\n\t}\n", join(v4))
+	}
+	if len(v6) > 0 {
+		fmt.Fprintf(&b, "\tif net.type = NET_IP6 then {\n\t\tif net ~ [ %s ] then accept;\n\t}\n", join(v6))
+	}
+	b.WriteString("\treject;\n}\n")
+	return b.String(), nil
+}
+
+func join(prefixes []*net.IPNet) string {
+	parts := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		parts = append(parts, p.String())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,160 @@
+package export_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/export"
+)
+
+func mustPrefixes(t *testing.T, cidrs ...string) []*net.IPNet {
+	t.Helper()
+	prefixes := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, p, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	prefixes := mustPrefixes(t, "1.1.1.0/24", "2606:4700::/32", "1.0.0.0/24")
+	tests := []struct {
+		name string
+		fn   func(string, []*net.IPNet) (string, error)
+		want string
+	}{
+		{
+			name: "cisco",
+			fn:   export.CiscoPrefixList,
+			want: "ip prefix-list AS13335 seq 5 permit 1.1.1.0/24\n" +
+				"ip prefix-list AS13335 seq 10 permit 1.0.0.0/24\n" +
+				"ipv6 prefix-list AS13335 seq 5 permit 2606:4700::/32\n",
+		},
+		{
+			name: "juniper",
+			fn:   export.JuniperPrefixList,
+			want: "set policy-options prefix-list AS13335 1.1.1.0/24\n" +
+				"set policy-options prefix-list AS13335 2606:4700::/32\n" +
+				"set policy-options prefix-list AS13335 1.0.0.0/24\n",
+		},
+		{
+			name: "junos policy",
+			fn:   export.JunOSPolicy,
+			want: "set policy-options policy-statement AS13335 term ipv4 from route-filter 1.1.1.0/24 exact\n" +
+				"set policy-options policy-statement AS13335 term ipv4 from route-filter 1.0.0.0/24 exact\n" +
+				"set policy-options policy-statement AS13335 term ipv4 then accept\n" +
+				"set policy-options policy-statement AS13335 term ipv6 from route-filter 2606:4700::/32 exact\n" +
+				"set policy-options policy-statement AS13335 term ipv6 then accept\n" +
+				"set policy-options policy-statement AS13335 then reject\n",
+		},
+		{
+			name: "bird",
+			fn:   export.BIRDFilter,
+			want: "filter AS13335 {\n" +
+				"\tif net.type = NET_IP4 then {\n\t\tif net ~ [ 1.1.1.0/24, 1.0.0.0/24 ] then accept;\n\t}\n" +
+				"\tif net.type = NET_IP6 then {\n\t\tif net ~ [ 2606:4700::/32 ] then accept;\n\t}\n" +
+				"\treject;\n}\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.fn("AS13335", prefixes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Got:\n%s\nWant:\n%s", got, tc.want)
+			}
+			if _, err := tc.fn("bad name", prefixes); err == nil {
+				t.Error("Expected error for bad name, but no error returned")
+			}
+		})
+	}
+}
+
+func TestRPSL(t *testing.T) {
+	t.Parallel()
+	prefixes := mustPrefixes(t, "1.1.1.0/24", "2606:4700::/32", "1.1.1.0/24")
+	got, err := export.RPSL(13335, prefixes, export.RPSLTemplate{Source: "ARIN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "route:          1.1.1.0/24\n" +
+		"descr:          AS13335 route\n" +
+		"origin:         AS13335\n" +
+		"mnt-by:         MAINT-AS13335\n" +
+		"source:         ARIN\n" +
+		"\n" +
+		"route6:         2606:4700::/32\n" +
+		"descr:          AS13335 route\n" +
+		"origin:         AS13335\n" +
+		"mnt-by:         MAINT-AS13335\n" +
+		"source:         ARIN\n"
+	if got != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, want)
+	}
+
+	if _, err := export.RPSL(13335, prefixes, export.RPSLTemplate{Descr: "x\nsource: EVIL"}); err == nil {
+		t.Error("Expected error for newline in template, but no error returned")
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	t.Parallel()
+	invalids := map[int][]*net.IPNet{
+		64501: mustPrefixes(t, "23.1.0.0/16", "2001:db8::/48"),
+		64502: mustPrefixes(t, "23.1.0.0/16", "192.0.2.0/24"),
+	}
+
+	got, err := export.Blocklist(invalids, export.BlocklistOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "23.1.0.0/16\n192.0.2.0/24\n2001:db8::/48\n"; got != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, want)
+	}
+
+	got, err = export.Blocklist(invalids, export.BlocklistOptions{Family: 4, Comment: ";", Header: "ROA invalids", Origins: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "; ROA invalids\n" +
+		"23.1.0.0/16 ; AS64501 AS64502\n" +
+		"192.0.2.0/24 ; AS64502\n"
+	if got != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, want)
+	}
+
+	if _, err := export.Blocklist(invalids, export.BlocklistOptions{Family: 5}); err == nil {
+		t.Error("Expected error for family 5, but no error returned")
+	}
+}
+
+func TestRPZ(t *testing.T) {
+	t.Parallel()
+	invalids := map[int][]*net.IPNet{
+		64501: mustPrefixes(t, "23.1.0.0/16", "2001:db8::/48", "2001:db8:0:1::/64"),
+	}
+	got, err := export.RPZ(invalids, export.RPZOptions{Action: export.RPZDrop, Serial: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "$TTL 3600\n" +
+		"@ SOA localhost. hostmaster.localhost. 7 3600 600 86400 3600\n" +
+		"@ NS localhost.\n" +
+		"16.0.0.1.23.rpz-ip CNAME rpz-drop. ; AS64501\n" +
+		"48.zz.db8.2001.rpz-ip CNAME rpz-drop. ; AS64501\n" +
+		"64.zz.1.0.db8.2001.rpz-ip CNAME rpz-drop. ; AS64501\n"
+	if got != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, want)
+	}
+
+	if _, err := export.RPZ(invalids, export.RPZOptions{NS: "bad name"}); err == nil {
+		t.Error("Expected error for invalid NS, but no error returned")
+	}
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// RPSLTemplate holds the attributes filled into each generated object.
+// Empty fields get a default.
+type RPSLTemplate struct {
+	Descr      string // defaults to "AS<asn> route"
+	Maintainer string // defaults to "MAINT-AS<asn>"
+	Source     string // defaults to "RADB"
+}
+
+// RPSL returns route and route6 objects for prefixes originated by asn,
+// separated by blank lines, ready to be edited and submitted to an IRR.
+// Duplicate prefixes are only written once.
+func RPSL(asn uint32, prefixes []*net.IPNet, tmpl RPSLTemplate) (string, error) {
+	if asn == 0 {
+		return "", errors.New("invalid origin ASN 0")
+	}
+	if tmpl.Descr == "" {
+		tmpl.Descr = fmt.Sprintf("AS%d route", asn)
+	}
+	if tmpl.Maintainer == "" {
+		tmpl.Maintainer = fmt.Sprintf("MAINT-AS%d", asn)
+	}
+	if tmpl.Source == "" {
+		tmpl.Source = "RADB"
+	}
+	// A newline would let a value start a new attribute.
+	for _, v := range []string{tmpl.Descr, tmpl.Maintainer, tmpl.Source} {
+		if strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("template value %q contains a newline", v)
+		}
+	}
+	if err := checkName(tmpl.Maintainer); err != nil {
+		return "", fmt.Errorf("maintainer: %w", err)
+	}
+	if err := checkName(tmpl.Source); err != nil {
+		return "", fmt.Errorf("source: %w", err)
+	}
+
+	var objects []string
+	seen := make(map[string]bool, len(prefixes))
+	for _, p := range prefixes {
+		if seen[p.String()] {
+			continue
+		}
+		seen[p.String()] = true
+
+		class := "route"
+		if p.IP.To4() == nil {
+			class = "route6"
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "%-16s%s\n", class+":", p)
+		fmt.Fprintf(&b, "%-16s%s\n", "descr:", tmpl.Descr)
+		fmt.Fprintf(&b, "%-16sAS%d\n", "origin:", asn)
+		fmt.Fprintf(&b, "%-16s%s\n", "mnt-by:", tmpl.Maintainer)
+		fmt.Fprintf(&b, "%-16s%s\n", "source:", tmpl.Source)
+		objects = append(objects, b.String())
+	}
+
+	return strings.Join(objects, "\n"), nil
+}
@@ -0,0 +1,86 @@
+package bgpstuff
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// WithLocalOriginFallback makes GetOrigin answer from the prefixes returned
+// by earlier GetSourced calls when the API can't be reached: the request
+// failed in transport, timed out or got a 5xx status. Other errors, and
+// those from a closed client or a call whose context is done, are returned
+// as they are. The answer is
+// the origin of the longest matching prefix, and its Meta has Source set to
+// SourceLocal and FetchedAt set to when that prefix was fetched.
+func WithLocalOriginFallback() Option {
+	return func(c *Client) {
+		c.origins = newOriginIndex()
+	}
+}
+
+// originIndex maps prefixes to the AS sourcing them.
+type originIndex struct {
+	mu       sync.RWMutex
+	prefixes map[netip.Prefix]originEntry
+	byASN    map[int][]netip.Prefix
+}
+
+type originEntry struct {
+	asn     int
+	fetched time.Time
+}
+
+func newOriginIndex() *originIndex {
+	return &originIndex{
+		prefixes: make(map[netip.Prefix]originEntry),
+		byASN:    make(map[int][]netip.Prefix),
+	}
+}
+
+// update replaces the prefixes sourced by asn.
+func (oi *originIndex) update(asn int, prefixes []netip.Prefix, fetched time.Time) {
+	oi.mu.Lock()
+	defer oi.mu.Unlock()
+	for _, p := range oi.byASN[asn] {
+		if oi.prefixes[p].asn == asn {
+			delete(oi.prefixes, p)
+		}
+	}
+	for _, p := range prefixes {
+		oi.prefixes[p.Masked()] = originEntry{asn: asn, fetched: fetched}
+	}
+	oi.byASN[asn] = prefixes
+}
+
+// lookup returns the entry for the longest prefix containing addr.
+func (oi *originIndex) lookup(addr netip.Addr) (originEntry, bool) {
+	oi.mu.RLock()
+	defer oi.mu.RUnlock()
+	for bits := addr.BitLen(); bits >= 0; bits-- {
+		p, _ := addr.Prefix(bits)
+		if entry, ok := oi.prefixes[p]; ok {
+			return entry, true
+		}
+	}
+	return originEntry{}, false
+}
+
+// localOrigin answers an origin lookup for ip from the index when the API
+// could not be reached, failing with err. Calls whose own context is done
+// are not answered.
+func (c *Client) localOrigin(call *callOptions, ip string, err error) (int, bool) {
+	if c.origins == nil || call.ctx.Err() != nil || !unreachable(err) {
+		return 0, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return 0, false
+	}
+	entry, ok := c.origins.lookup(addr.Unmap())
+	if !ok {
+		return 0, false
+	}
+	call.setMeta(Meta{FetchedAt: entry.fetched, Source: SourceLocal})
+	return entry.asn, true
+}
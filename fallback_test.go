@@ -0,0 +1,51 @@
+package bgpstuff
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLocalOriginFallback(t *testing.T) {
+	t.Parallel()
+	var down int32
+	handler := downAPI(&down, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sourced/13335" {
+			fmt.Fprint(w, `{"Response":{"Sourced":{"Ipv4":2,"Ipv6":0,"Prefixes":["1.0.0.0/8","1.1.1.0/24"]}}}`)
+			return
+		}
+		fakeAPI(w, r)
+	})
+	c := newTestClient(t, handler, WithLocalOriginFallback())
+	if _, _, _, err := c.GetSourced(13335); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&down, apiDown)
+
+	var meta Meta
+	origin, err := c.GetOrigin("1.1.1.1", WithMeta(&meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != 13335 || meta.Source != SourceLocal {
+		t.Errorf("Got: %d from %q, Want: 13335 from %q", origin, meta.Source, SourceLocal)
+	}
+	if _, err := c.GetOrigin("8.8.8.8"); err == nil {
+		t.Error("Expected an error for an IP not in the local index")
+	}
+
+	// Errors other than the API being down are not answered locally.
+	atomic.StoreInt32(&down, apiGone)
+	if _, err := c.GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected an error for a 404, but no error returned")
+	}
+	atomic.StoreInt32(&down, apiDown)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetOrigin("1.1.1.1"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Got: %v, Want: %v", err, ErrClientClosed)
+	}
+}
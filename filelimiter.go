@@ -0,0 +1,207 @@
+package bgpstuff
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long to wait between attempts to take a FileLimiter's lock file, and
+// how old a lock file must be before it is taken to be left behind by a
+// process that died holding it.
+const (
+	fileLockRetry = 5 * time.Millisecond
+	fileLockStale = 10 * time.Second
+)
+
+// FileLimiter is a Limiter that keeps the times of recent requests in a
+// file, so the budget carries over when a program restarts. A CLI tool in
+// a crash-restart loop then can't send a fresh burst each time it starts.
+// It allows rpm requests in any minute. Use it with WithLimiter.
+//
+// Several processes, or several FileLimiters in one, may share a path and
+// with it the budget. Each Wait takes a lock file next to the state file,
+// named path plus ".lock", then reads the file afresh before recording its
+// request.
+type FileLimiter struct {
+	mu     sync.Mutex
+	path   string
+	rpm    int
+	window time.Duration
+	stamps []time.Time
+}
+
+var _ WaitEstimator = (*FileLimiter)(nil)
+
+// NewFileLimiter returns a FileLimiter keeping its state in path, starting
+// from any requests recorded there in the last minute.
+func NewFileLimiter(path string, rpm int) (*FileLimiter, error) {
+	if rpm <= 0 {
+		return nil, errors.New("requests per minute must be positive")
+	}
+	l := &FileLimiter{path: path, rpm: rpm, window: time.Minute}
+	stamps, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	l.stamps = stamps
+	l.prune(time.Now())
+	return l, nil
+}
+
+// Wait blocks until a request may be made, then records it.
+func (l *FileLimiter) Wait(ctx context.Context) error {
+	for {
+		wait, err := l.take(ctx)
+		if err != nil || wait == 0 {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take records a request if there is room for one, and otherwise returns
+// how long until there may be.
+func (l *FileLimiter) take(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := l.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	stamps, err := l.load()
+	if err != nil {
+		return 0, err
+	}
+	l.stamps = stamps
+	now := time.Now()
+	l.prune(now)
+	if len(l.stamps) < l.rpm {
+		l.stamps = append(l.stamps, now)
+		return 0, l.save()
+	}
+	return l.stamps[0].Add(l.window).Sub(now), nil
+}
+
+// EstimateWait returns how long the next Wait would block.
+func (l *FileLimiter) EstimateWait() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if stamps, err := l.load(); err == nil {
+		l.stamps = stamps
+	}
+	now := time.Now()
+	l.prune(now)
+	if len(l.stamps) < l.rpm {
+		return 0
+	}
+	return l.stamps[0].Add(l.window).Sub(now)
+}
+
+// lock takes the lock file, waiting for any other holder to let it go, and
+// returns a function that lets it go.
+func (l *FileLimiter) lock(ctx context.Context) (func(), error) {
+	lockPath := l.path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("limiter lock: %w", err)
+		}
+		if fi, err := os.Stat(lockPath); err == nil && time.Since(fi.ModTime()) > fileLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+
+		timer := time.NewTimer(fileLockRetry)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// load reads the recorded requests from the state file, oldest first.
+func (l *FileLimiter) load() ([]time.Time, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var stamps []time.Time
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, line)
+		if err != nil {
+			return nil, fmt.Errorf("limiter state %s: %w", l.path, err)
+		}
+		stamps = append(stamps, t)
+	}
+	return stamps, scanner.Err()
+}
+
+// prune drops requests that have left the window. l.mu must be held.
+func (l *FileLimiter) prune(now time.Time) {
+	i := 0
+	for i < len(l.stamps) && now.Sub(l.stamps[i]) >= l.window {
+		i++
+	}
+	l.stamps = l.stamps[i:]
+}
+
+// save writes the recorded requests to the state file, replacing it whole
+// so a crash can't leave it half written. l.mu and the lock file must be
+// held.
+func (l *FileLimiter) save() error {
+	var b strings.Builder
+	for _, t := range l.stamps {
+		b.WriteString(t.Format(time.RFC3339Nano))
+		b.WriteByte('\n')
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return fmt.Errorf("limiter state: %w", err)
+	}
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("limiter state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("limiter state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("limiter state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileLimiter(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "limiter")
+	l, err := NewFileLimiter(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A restarted program picks up where the last one left off.
+	restarted, err := NewFileLimiter(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.EstimateWait(); got < 30*time.Second {
+		t.Errorf("Got wait: %v, Want about a minute", got)
+	}
+	c := newTestClient(t, fakeAPI, WithLimiter(restarted))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.GetOrigin("1.1.1.1", WithContext(ctx)); !errors.Is(err, ErrWouldExceedDeadline) {
+		t.Errorf("Expected ErrWouldExceedDeadline, got: %v", err)
+	}
+
+	fresh, err := NewFileLimiter(filepath.Join(t.TempDir(), "limiter"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fresh.EstimateWait(); got != 0 {
+		t.Errorf("Got wait: %v, Want: 0", got)
+	}
+}
+
+func TestFileLimiterShared(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "limiter")
+	var limiters []*FileLimiter
+	for i := 0; i < 3; i++ {
+		l, err := NewFileLimiter(path, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limiters = append(limiters, l)
+	}
+
+	// Limiters sharing a file share one budget, however their waits
+	// interleave.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(l *FileLimiter) {
+			defer wg.Done()
+			if err := l.Wait(ctx); err == nil {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}(limiters[i%len(limiters)])
+	}
+	wg.Wait()
+	if allowed != 10 {
+		t.Errorf("Got %d requests allowed, Want 10", allowed)
+	}
+	for _, l := range limiters {
+		if got := l.EstimateWait(); got < 30*time.Second {
+			t.Errorf("Got wait: %v, Want about a minute", got)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package bgpstuff
+
+import "fmt"
+
+// GetGeo returns the country of the network announcing ip. bgpstuff.net has
+// no per-prefix geolocation, so this is the country the origin AS is
+// registered in, taken from its AS locale. Only Location.Country is set.
+// It returns nil if there is no route for ip.
+func (c *Client) GetGeo(ip string, opts ...CallOption) (*Location, error) {
+	origin, err := c.GetOrigin(ip, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if origin == 0 {
+		return nil, nil
+	}
+
+	if info, ok := c.GetASInfo(origin); ok {
+		return &Location{Country: info.ASLocale}, nil
+	}
+
+	resp, err := c.getRequest(newCallOptions(opts), "asname", fmt.Sprint(origin))
+	if err != nil {
+		return nil, lookupError("asname", fmt.Sprint(origin), err)
+	}
+
+	return &Location{Country: resp.Data.ASLocale}, nil
+}
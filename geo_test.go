@@ -0,0 +1,22 @@
+package bgpstuff
+
+import (
+	"testing"
+)
+
+func TestGetGeo(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+	loc, err := c.GetGeo("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc == nil || loc.Country != "US" {
+		t.Errorf("Got: %+v, Want country US", loc)
+	}
+
+	loc, err = c.GetGeo("19.1.1.1")
+	if err != nil || loc != nil {
+		t.Errorf("Got: %+v %v, Want no location", loc, err)
+	}
+}
@@ -0,0 +1,42 @@
+package bgpstuff
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Get requests endpoint with args from the API and decodes the Response
+// object of the answer into a T. It lets new server endpoints be used, with
+// a struct of the caller's own, before this package has a method for them:
+//
+//	type Peers struct{ Peers []string }
+//	p, err := bgpstuff.Get[Peers](c, "peers")
+//
+// The request waits for the rate limiter and has the client's timeout like
+// any other, but the answer is not cached.
+func Get[T any](c *Client, endpoint string, args ...string) (T, error) {
+	var result T
+	urls := append([]string{endpoint}, args...)
+
+	var raw struct {
+		Response json.RawMessage
+	}
+	decode := func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&raw)
+	}
+	call := newCallOptions(nil)
+	err := c.fetch(call, endpoint, c.getURI(urls), decode)
+	if err == nil && len(raw.Response) > 0 {
+		var envelope struct{ Action string }
+		json.Unmarshal(raw.Response, &envelope)
+		if err = checkAction(endpoint, envelope.Action); err == nil {
+			err = json.Unmarshal(raw.Response, &result)
+		}
+	}
+	c.metrics.request(endpoint, call.requestTime(), err)
+	if err != nil {
+		return result, lookupError(endpoint, strings.Join(args, "/"), err)
+	}
+	return result, nil
+}
@@ -0,0 +1,33 @@
+package bgpstuff
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/peers/1.1.1.1":
+			fmt.Fprint(w, `{"Response":{"Action":"peers","Peers":["174","3356"]}}`)
+		case "/wrong":
+			fmt.Fprint(w, `{"Response":{"Action":"route"}}`)
+		}
+	})
+
+	type peers struct{ Peers []string }
+	got, err := Get[peers](c, "peers", "1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"174", "3356"}; !reflect.DeepEqual(got.Peers, want) {
+		t.Errorf("Got: %v, Want: %v", got.Peers, want)
+	}
+	if _, err := Get[peers](c, "wrong"); !errors.Is(err, ErrUnexpectedResponse) {
+		t.Errorf("Got error: %v, Want: %v", err, ErrUnexpectedResponse)
+	}
+}
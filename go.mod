@@ -1,9 +1,9 @@
 module github.com/mellowdrifter/go-bgpstuff.net
 
-go 1.16
+go 1.18
 
 require (
 	github.com/google/go-cmp v0.5.4
 	github.com/mellowdrifter/bogons v1.0.0
-	golang.org/x/time v0.0.0-20220411224347-583f2d630306
+	golang.org/x/time v0.5.0
 )
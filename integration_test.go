@@ -0,0 +1,187 @@
+//go:build integration
+
+package bgpstuff_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/fs"
+	"net"
+	"os"
+	"testing"
+
+	bgpstuff "github.com/mellowdrifter/go-bgpstuff.net"
+	"github.com/mellowdrifter/go-bgpstuff.net/bgpstufftest"
+	"golang.org/x/time/rate"
+)
+
+// newIntegrationClient starts a local server with the default fixture and
+// returns a client for it that isn't rate limited.
+func newIntegrationClient(t *testing.T) *bgpstuff.Client {
+	t.Helper()
+	srv := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture())
+	return srv.Client(bgpstuff.WithLimiter(rate.NewLimiter(rate.Inf, 1)))
+}
+
+func TestIntegrationIPLookups(t *testing.T) {
+	c := newIntegrationClient(t)
+	tests := []struct {
+		ip     string
+		route  string
+		origin int
+		path   []int
+		roa    string
+	}{
+		{ip: "1.1.1.1", route: "1.1.1.0/24", origin: 13335, path: []int{174, 13335}, roa: "VALID"},
+		{ip: "19.1.1.1", route: "19.0.0.0/8", origin: 3389, path: []int{3356, 3389}, roa: "UNKNOWN"},
+		{ip: "23.1.2.3", route: "23.1.0.0/16", origin: 4134, path: []int{174, 4837, 4837, 4134}, roa: "INVALID"},
+		{ip: "2606:4700::1111", route: "2606:4700::/32", origin: 13335, path: []int{6939, 13335}, roa: "VALID"},
+		{ip: "9.9.9.9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			route, err := c.GetRoute(tc.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := routeString(route); got != tc.route {
+				t.Errorf("Got route: %q, Want: %q", got, tc.route)
+			}
+			origin, err := c.GetOrigin(tc.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if origin != tc.origin {
+				t.Errorf("Got origin: %d, Want: %d", origin, tc.origin)
+			}
+			path, _, err := c.GetASPath(tc.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(path) != len(tc.path) {
+				t.Fatalf("Got path: %v, Want: %v", path, tc.path)
+			}
+			for i := range path {
+				if path[i] != tc.path[i] {
+					t.Errorf("Got path: %v, Want: %v", path, tc.path)
+					break
+				}
+			}
+			roa, err := c.GetROA(tc.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if roa != tc.roa {
+				t.Errorf("Got ROA: %q, Want: %q", roa, tc.roa)
+			}
+		})
+	}
+}
+
+func TestIntegrationASLookups(t *testing.T) {
+	c := newIntegrationClient(t)
+
+	name, err := c.GetASName(13335)
+	if err != nil || name != "CLOUDFLARENET" {
+		t.Errorf("Got name: %q %v, Want: CLOUDFLARENET", name, err)
+	}
+	if err := c.GetASNames(); err != nil {
+		t.Fatal(err)
+	}
+	if info, ok := c.GetASInfo(15169); !ok || info.ASName != "GOOGLE" {
+		t.Errorf("Got: %+v, Want GOOGLE from the loaded table", info)
+	}
+
+	prefixes, v4, v6, err := c.GetSourced(13335)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefixes) != 2 || v4 != 1 || v6 != 1 {
+		t.Errorf("Got: %v %d %d, Want two prefixes, one of each family", prefixes, v4, v6)
+	}
+
+	v4, v6, err = c.GetTotals()
+	if err != nil || v4 != 4 || v6 != 1 {
+		t.Errorf("Got totals: %d %d %v, Want: 4 1", v4, v6, err)
+	}
+
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	invalid, err := c.GetInvalid(4134)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid) != 1 || invalid[0].String() != "23.1.0.0/16" {
+		t.Errorf("Got invalids: %v, Want: [23.1.0.0/16]", invalid)
+	}
+}
+
+// recorded holds responses captured from a real server by
+// bgpstufftest.Record. Refresh them with make record.
+const recorded = "testdata/recorded"
+
+var record = flag.String("record", "", "base URL of a real server to record responses from before replaying them")
+
+// TestIntegrationReplay runs lookups against responses recorded from a real
+// server, so a change in what the server sends fails here even though the
+// fixture server, which encodes the client's own types, still agrees with
+// the client. Only answers that rarely change are checked.
+func TestIntegrationReplay(t *testing.T) {
+	if *record != "" {
+		if err := bgpstufftest.Record(context.Background(), *record, recorded, bgpstufftest.DefaultRecordedPaths); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(recorded); errors.Is(err, fs.ErrNotExist) {
+		t.Skipf("no recorded responses in %s, run make record", recorded)
+	}
+	srv := bgpstufftest.StartReplayServer(t, recorded)
+	c := srv.Client(bgpstuff.WithLimiter(rate.NewLimiter(rate.Inf, 1)))
+
+	if err := c.VerifyCompatibility(context.Background()); err != nil {
+		t.Error(err)
+	}
+	for _, ip := range []string{"1.1.1.1", "2606:4700::1111"} {
+		route, err := c.GetRoute(ip)
+		if err != nil || route == nil || !route.Contains(net.ParseIP(ip)) {
+			t.Errorf("GetRoute(%s) = %v, %v, Want a route covering it", ip, route, err)
+		}
+		path, _, err := c.GetASPath(ip)
+		if err != nil || len(path) == 0 || path[len(path)-1] != 13335 {
+			t.Errorf("GetASPath(%s) = %v, %v, Want a path to AS13335", ip, path, err)
+		}
+	}
+	if origin, err := c.GetOrigin("1.1.1.1"); err != nil || origin != 13335 {
+		t.Errorf("Got origin: %d, %v, Want: 13335", origin, err)
+	}
+	if roa, err := c.GetROA("1.1.1.1"); err != nil || roa == "" {
+		t.Errorf("Got ROA: %q, %v, Want a status", roa, err)
+	}
+	for asn, want := range map[int]string{13335: "CLOUDFLARENET", 15169: "GOOGLE"} {
+		if name, err := c.GetASName(asn); err != nil || name != want {
+			t.Errorf("GetASName(%d) = %q, %v, Want: %s", asn, name, err, want)
+		}
+	}
+	if prefixes, v4, v6, err := c.GetSourced(13335); err != nil || len(prefixes) == 0 || v4 == 0 || v6 == 0 {
+		t.Errorf("Got sourced: %d prefixes, %d IPv4, %d IPv6, %v, Want some of each", len(prefixes), v4, v6, err)
+	}
+	if v4, v6, err := c.GetTotals(); err != nil || v4 < 800000 || v6 < 100000 {
+		t.Errorf("Got totals: %d %d %v, Want a full table", v4, v6, err)
+	}
+}
+
+func TestIntegrationCompatibility(t *testing.T) {
+	c := newIntegrationClient(t)
+	if err := c.VerifyCompatibility(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func routeString(n *net.IPNet) string {
+	if n == nil {
+		return ""
+	}
+	return n.String()
+}
@@ -0,0 +1,109 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClientClosed is returned by lookups that need a request once the
+// client has been closed.
+var ErrClientClosed = errors.New("client is closed")
+
+// lifecycle tracks the work a client has running so it can be shut down.
+type lifecycle struct {
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+	nextID int
+	// requests are cancelled if they are still running when the shutdown
+	// deadline passes; background work such as Subscribe is cancelled as
+	// soon as shutdown starts.
+	requests   map[int]context.CancelFunc
+	background map[int]context.CancelFunc
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		requests:   make(map[int]context.CancelFunc),
+		background: make(map[int]context.CancelFunc),
+	}
+}
+
+// begin registers work running under ctx. It returns a context that is
+// cancelled when shutdown needs the work to stop, and a func to call when
+// the work is done.
+func (l *lifecycle) begin(ctx context.Context, background bool) (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, nil, ErrClientClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	set := l.requests
+	if background {
+		set = l.background
+	}
+	id := l.nextID
+	l.nextID++
+	set[id] = cancel
+	l.wg.Add(1)
+
+	end := func() {
+		l.mu.Lock()
+		delete(set, id)
+		l.mu.Unlock()
+		cancel()
+		l.wg.Done()
+	}
+	return ctx, end, nil
+}
+
+// Shutdown stops the client. Lookups that need a request fail with
+// ErrClientClosed from then on, and Subscribe watchers stop straight away,
+// while lookups already in flight are given until ctx is done to finish
+// before they are cancelled. Idle connections are then closed. It returns
+// ctx's error if lookups had to be cancelled.
+func (c *Client) Shutdown(ctx context.Context) error {
+	l := c.life
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	for _, cancel := range l.background {
+		cancel()
+	}
+	l.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		l.mu.Lock()
+		for _, cancel := range l.requests {
+			cancel()
+		}
+		l.mu.Unlock()
+		<-drained
+	}
+
+	c.httpClient.CloseIdleConnections()
+	return err
+}
+
+// Close is Shutdown with a deadline of the default request timeout, which
+// is as long as any lookup would have run anyway.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	return c.Shutdown(ctx)
+}
@@ -0,0 +1,82 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/origin/8.8.8.8" {
+			started <- struct{}{}
+			<-release
+		}
+		fakeAPI(w, r)
+	}, WithPollInterval(time.Hour))
+
+	events, err := c.Subscribe(context.Background(), TopicTotals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrigin("8.8.8.8")
+		inflight <- err
+	}()
+	<-started
+
+	// The in-flight lookup is allowed to finish within the deadline.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != nil {
+		t.Errorf("No error expected, but got error: %v", err)
+	}
+	if err := <-inflight; err != nil {
+		t.Errorf("In-flight lookup failed: %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("Subscription still open after Shutdown")
+	}
+	if _, err := c.GetOrigin("1.1.1.1"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{}, 1)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	})
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrigin("1.1.1.1")
+		inflight <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if err := <-inflight; err == nil {
+		t.Error("Expected the in-flight lookup to be cancelled")
+	}
+}
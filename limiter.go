@@ -0,0 +1,91 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter paces requests to the API. Wait blocks until a request may be
+// made, or returns an error if ctx is done first. By default each Client
+// uses its own token bucket allowing 30 requests per minute. FileLimiter
+// lets several processes on one host share that budget; a Limiter backed
+// by a shared service such as Redis could do the same across hosts.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// WithLimiter replaces the client's default rate limiter.
+func WithLimiter(l Limiter) Option {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// WithMaxConcurrent limits the client to n requests in flight at once,
+// whatever the rate limiter allows. A burst of tokens then can't turn into
+// hundreds of open sockets. Requests over the limit wait for a slot, or
+// until their context is done.
+func WithMaxConcurrent(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// ErrWouldExceedDeadline is returned instead of waiting for the rate
+// limiter when the wait would run past the call's context deadline.
+var ErrWouldExceedDeadline = errors.New("rate limit wait would exceed deadline")
+
+// WaitEstimator is implemented by Limiters that can tell how long the next
+// Wait would block without consuming anything.
+type WaitEstimator interface {
+	EstimateWait() time.Duration
+}
+
+// rateLimiter is the default Limiter.
+type rateLimiter struct {
+	*rate.Limiter
+}
+
+func newRateLimiter(rpm int) *rateLimiter {
+	return &rateLimiter{rate.NewLimiter(rate.Every(time.Minute/time.Duration(rpm)), rpm)}
+}
+
+// EstimateWait works out how long until the bucket holds a whole token.
+func (l *rateLimiter) EstimateWait() time.Duration {
+	missing := 1 - l.Tokens()
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / float64(l.Limit()) * float64(time.Second))
+}
+
+// EstimateWait returns how long the next request would wait for the rate
+// limiter. It returns zero if the Limiter in use can't tell.
+func (c *Client) EstimateWait() time.Duration {
+	if e, ok := c.limiter.(WaitEstimator); ok {
+		return e.EstimateWait()
+	}
+	return 0
+}
+
+// wait blocks until the rate limiter allows a request, letting higher
+// priority requests go first. If ctx has a deadline that the wait would
+// pass, it fails with ErrWouldExceedDeadline straight away rather than
+// stalling first.
+func (c *Client) wait(ctx context.Context, p Priority) error {
+	if err := c.sched.acquire(ctx, p); err != nil {
+		return err
+	}
+	defer c.sched.release()
+	if deadline, ok := ctx.Deadline(); ok {
+		if time.Now().Add(c.EstimateWait()).After(deadline) {
+			return ErrWouldExceedDeadline
+		}
+	}
+	return c.limiter.Wait(ctx)
+}
@@ -0,0 +1,79 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithLimiter(t *testing.T) {
+	t.Parallel()
+	l := &countingLimiter{}
+	c := newTestClient(t, fakeAPI, WithLimiter(l))
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if l.waits != 1 {
+		t.Errorf("Got %d waits, Want 1", l.waits)
+	}
+}
+
+func TestWouldExceedDeadline(t *testing.T) {
+	t.Parallel()
+	l := newRateLimiter(1)
+	c := newTestClient(t, fakeAPI, WithLimiter(l))
+	if got := c.EstimateWait(); got != 0 {
+		t.Errorf("Got wait: %v, Want: 0", got)
+	}
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.EstimateWait(); got < 30*time.Second {
+		t.Errorf("Got wait: %v, Want about a minute", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.GetOrigin("1.1.1.1", WithContext(ctx), NoCache()); !errors.Is(err, ErrWouldExceedDeadline) {
+		t.Errorf("Expected ErrWouldExceedDeadline, got: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Lookup blocked instead of failing fast")
+	}
+}
+
+func TestWithMaxConcurrent(t *testing.T) {
+	t.Parallel()
+	var inFlight, most int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fakeAPI(w, r)
+	}
+	c := newTestClient(t, handler, WithLimiter(&countingLimiter{}), WithMaxConcurrent(2))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetOrigin("1.1.1.1", NoCache()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&most); got != 2 {
+		t.Errorf("Got %d requests in flight, Want 2", got)
+	}
+}
@@ -0,0 +1,36 @@
+package bgpstuff
+
+import "net"
+
+// RouteLookuper finds the route covering an IP and the AS originating it.
+type RouteLookuper interface {
+	GetRoute(ip string, opts ...CallOption) (*net.IPNet, error)
+	GetOrigin(ip string, opts ...CallOption) (int, error)
+}
+
+// ROAChecker finds the RPKI validation state of the route covering an IP.
+type ROAChecker interface {
+	GetROA(ip string, opts ...CallOption) (string, error)
+}
+
+// ASNamer finds the name of an AS.
+type ASNamer interface {
+	GetASName(asn int, opts ...CallOption) (string, error)
+}
+
+// Lookuper answers the basic IP and AS lookups. *Client is one, and package
+// altsource has adapters backed by other data sources, so code written
+// against Lookuper can switch between them or compare their answers. Code
+// needing less can take RouteLookuper, ROAChecker or ASNamer instead, so
+// a test fake need only have the methods used.
+type Lookuper interface {
+	RouteLookuper
+	ASNamer
+}
+
+var (
+	_ Lookuper      = (*Client)(nil)
+	_ RouteLookuper = (*Client)(nil)
+	_ ROAChecker    = (*Client)(nil)
+	_ ASNamer       = (*Client)(nil)
+)
@@ -0,0 +1,110 @@
+package bgpstuff
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Meta describes where the answer to a lookup came from. Pass a pointer to
+// one with WithMeta to have it filled in.
+type Meta struct {
+	// Stale is true if the API could not be reached and the answer was
+	// served from the cache kept by WithStaleIfError, or from the store
+	// kept by WithStore.
+	Stale bool
+	// Cached is true if the answer came from the client's cache rather
+	// than a request made for this call.
+	Cached bool
+	// FetchedAt is when the answer, or the table it was found in, was
+	// fetched from the API.
+	FetchedAt time.Time
+	// Source says what answered the lookup.
+	Source Source
+
+	// Action is the action the server says it performed, such as "route".
+	Action string
+	// CacheTime is set if the server answered from its own cache, and is
+	// when it cached the answer.
+	CacheTime time.Time
+	// RTT is how long the request that fetched the answer took to get
+	// response headers back, covering the network and the server.
+	RTT time.Duration
+	// ServerTiming holds the durations the server reported in its
+	// Server-Timing header, by metric name. Comparing them with RTT shows
+	// whether time went on the network or on the server.
+	ServerTiming map[string]time.Duration
+}
+
+// Source is where the answer to a lookup came from.
+type Source string
+
+// Sources set in Meta.
+const (
+	// SourceAPI answers came from a request made to the API for the call,
+	// or shared with an identical call by WithCoalesce.
+	SourceAPI Source = "api"
+	// SourceCache answers came from the client's cache while still fresh.
+	// See WithCacheTTL.
+	SourceCache Source = "cache"
+	// SourceStale answers were fetched earlier and returned because the
+	// API could not be reached. See WithStaleIfError and WithStore.
+	SourceStale Source = "stale"
+	// SourceLocalTable answers came from a table loaded in bulk, such as
+	// the AS names loaded by GetASNames, without a request.
+	SourceLocalTable Source = "table"
+	// SourceLocal answers were worked out from data fetched earlier, as
+	// the API could not be reached. See WithLocalOriginFallback.
+	SourceLocal Source = "local"
+)
+
+// timing is how long a request took, as measured by the client and as
+// reported by the server.
+type timing struct {
+	rtt    time.Duration
+	server map[string]time.Duration
+}
+
+// responseMeta fills in m with what resp says about itself. The source is
+// SourceAPI unless m already has one.
+func responseMeta(resp *response, m Meta) Meta {
+	if m.Source == "" {
+		m.Source = SourceAPI
+	}
+	m.Action = resp.Data.Action
+	m.CacheTime = resp.Data.CacheTime
+	m.RTT = resp.timing.rtt
+	m.ServerTiming = resp.timing.server
+	return m
+}
+
+// parseServerTiming reads the metrics with a duration from Server-Timing
+// headers, such as "db;dur=53.2, app;desc=render;dur=4". Durations are in
+// milliseconds.
+func parseServerTiming(headers []string) map[string]time.Duration {
+	var timings map[string]time.Duration
+	for _, header := range headers {
+		for _, metric := range strings.Split(header, ",") {
+			params := strings.Split(metric, ";")
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+			for _, param := range params[1:] {
+				key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(key, "dur") {
+					continue
+				}
+				ms, err := strconv.ParseFloat(strings.Trim(value, `"`), 64)
+				if err != nil {
+					continue
+				}
+				if timings == nil {
+					timings = make(map[string]time.Duration)
+				}
+				timings[name] = time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+	}
+	return timings
+}
@@ -0,0 +1,117 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetaTiming(t *testing.T) {
+	t.Parallel()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server-Timing", `rib;dur=1.5, cache;desc="miss"`)
+		w.Header().Add("Server-Timing", "total;dur=2")
+		fmt.Fprint(w, `{"Response":{"Action":"origin","Origin":"13335","CacheTime":"2026-01-02T03:04:05Z"}}`)
+	}
+	c := newTestClient(t, handler, WithCacheTTL(time.Minute))
+	var meta Meta
+	if _, err := c.GetOrigin("1.1.1.1", WithMeta(&meta)); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Action != "origin" || meta.CacheTime.IsZero() || meta.RTT <= 0 {
+		t.Errorf("Got: %+v, Want action, cache time and RTT set", meta)
+	}
+	want := map[string]time.Duration{"rib": 1500 * time.Microsecond, "total": 2 * time.Millisecond}
+	if !reflect.DeepEqual(meta.ServerTiming, want) {
+		t.Errorf("Got timing: %v, Want: %v", meta.ServerTiming, want)
+	}
+
+	var cached Meta
+	if _, err := c.GetOrigin("1.1.1.1", WithMeta(&cached)); err != nil {
+		t.Fatal(err)
+	}
+	if !cached.Cached || cached.RTT != meta.RTT {
+		t.Errorf("Got: %+v, Want the cached answer's timing", cached)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	t.Parallel()
+	var down int32
+	handler := downAPI(&down, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/asnames" {
+			fmt.Fprint(w, `{"Response":{"Action":"asnames","ASNames":[{"ASN":13335,"ASName":"CLOUDFLARENET"},{"ASN":15169,"ASName":"GOOGLE"}]}}`)
+			return
+		}
+		fakeAPI(w, r)
+	})
+	cached := newTestClient(t, handler, WithCacheTTL(time.Hour))
+	stale := newTestClient(t, handler, WithStaleIfError(time.Hour))
+
+	tests := []struct {
+		desc   string
+		lookup func(meta *Meta) error
+		down   bool
+		want   Source
+	}{
+		{
+			desc: "first lookup",
+			lookup: func(meta *Meta) error {
+				_, err := cached.GetOrigin("1.1.1.1", WithMeta(meta))
+				return err
+			},
+			want: SourceAPI,
+		},
+		{
+			desc: "repeat lookup",
+			lookup: func(meta *Meta) error {
+				_, err := cached.GetOrigin("1.1.1.1", WithMeta(meta))
+				return err
+			},
+			want: SourceCache,
+		},
+		{
+			desc: "name from table",
+			lookup: func(meta *Meta) error {
+				if err := cached.GetASNames(); err != nil {
+					return err
+				}
+				_, err := cached.GetASName(13335, WithMeta(meta))
+				return err
+			},
+			want: SourceLocalTable,
+		},
+		{
+			desc: "lookup to keep",
+			lookup: func(meta *Meta) error {
+				_, err := stale.GetOrigin("1.1.1.1", WithMeta(meta))
+				return err
+			},
+			want: SourceAPI,
+		},
+		{
+			desc: "lookup while down",
+			lookup: func(meta *Meta) error {
+				_, err := stale.GetOrigin("1.1.1.1", WithMeta(meta))
+				return err
+			},
+			down: true,
+			want: SourceStale,
+		},
+	}
+	for _, tc := range tests {
+		if tc.down {
+			atomic.StoreInt32(&down, apiDown)
+		}
+		var meta Meta
+		if err := tc.lookup(&meta); err != nil {
+			t.Fatalf("%s: %v", tc.desc, err)
+		}
+		if meta.Source != tc.want || meta.FetchedAt.IsZero() {
+			t.Errorf("%s: Got source %q fetched at %v, Want: %q", tc.desc, meta.Source, meta.FetchedAt, tc.want)
+		}
+	}
+}
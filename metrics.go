@@ -0,0 +1,168 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metrics counts what the client has done, by endpoint.
+type metrics struct {
+	mu        sync.Mutex
+	started   time.Time
+	endpoints map[string]*endpointMetrics
+}
+
+type endpointMetrics struct {
+	requests  uint64
+	failures  uint64
+	cacheHits uint64
+	stale     uint64
+	evictions uint64
+	duration  time.Duration
+}
+
+func newMetrics() *metrics {
+	return &metrics{started: time.Now(), endpoints: make(map[string]*endpointMetrics)}
+}
+
+// endpoint returns the counters for name. m.mu must be held.
+func (m *metrics) endpoint(name string) *endpointMetrics {
+	e, ok := m.endpoints[name]
+	if !ok {
+		e = &endpointMetrics{}
+		m.endpoints[name] = e
+	}
+	return e
+}
+
+// request records a request made to the API.
+func (m *metrics) request(endpoint string, took time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.endpoint(endpoint)
+	e.requests++
+	e.duration += took
+	if err != nil {
+		e.failures++
+	}
+}
+
+// cacheHit records an answer served from the cache, stale or not.
+func (m *metrics) cacheHit(endpoint string, stale bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.endpoint(endpoint)
+	e.cacheHits++
+	if stale {
+		e.stale++
+	}
+}
+
+// eviction records a response evicted from a full cache.
+func (m *metrics) eviction(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoint(endpoint).evictions++
+}
+
+// ClientStats counts what a client has done since it was created.
+type ClientStats struct {
+	// Since is when the client was created.
+	Since time.Time
+	// Requests and Failures are totals across all endpoints.
+	Requests, Failures uint64
+	// Endpoints breaks the counts down by endpoint, such as "route".
+	Endpoints map[string]EndpointStats
+}
+
+// EndpointStats counts the lookups made against one endpoint.
+type EndpointStats struct {
+	// Requests is the number of requests made to the API, and Failures
+	// how many of those failed.
+	Requests, Failures uint64
+	// CacheHits is the number of lookups answered from the cache without
+	// a request, and Stale how many of those were stale answers served
+	// because the API failed.
+	CacheHits, Stale uint64
+	// Evictions is the number of responses evicted from the full cache.
+	Evictions uint64
+	// Duration is the total time spent on requests.
+	Duration time.Duration
+}
+
+// Stats returns the client's request counts, so applications can enforce
+// their own quotas against the API and alert before hitting its limits.
+func (c *Client) Stats() ClientStats {
+	return c.metrics.stats()
+}
+
+func (m *metrics) stats() ClientStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := ClientStats{
+		Since:     m.started,
+		Endpoints: make(map[string]EndpointStats, len(m.endpoints)),
+	}
+	for name, e := range m.endpoints {
+		stats.Requests += e.requests
+		stats.Failures += e.failures
+		stats.Endpoints[name] = EndpointStats{
+			Requests:  e.requests,
+			Failures:  e.failures,
+			CacheHits: e.cacheHits,
+			Stale:     e.stale,
+			Evictions: e.evictions,
+			Duration:  e.duration,
+		}
+	}
+	return stats
+}
+
+// MetricsHandler returns a handler serving the client's counters in the
+// Prometheus text exposition format, ready to be mounted on /metrics.
+func (c *Client) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		c.metrics.write(w)
+	})
+}
+
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.endpoints))
+	for name := range m.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	series := []struct {
+		name, help, kind string
+		value            func(*endpointMetrics) string
+	}{
+		{"bgpstuff_requests_total", "Requests made to the API.", "counter",
+			func(e *endpointMetrics) string { return fmt.Sprint(e.requests) }},
+		{"bgpstuff_request_failures_total", "Requests to the API that failed.", "counter",
+			func(e *endpointMetrics) string { return fmt.Sprint(e.failures) }},
+		{"bgpstuff_request_duration_seconds_total", "Time spent on requests to the API.", "counter",
+			func(e *endpointMetrics) string { return fmt.Sprint(e.duration.Seconds()) }},
+		{"bgpstuff_cache_hits_total", "Lookups answered from the cache.", "counter",
+			func(e *endpointMetrics) string { return fmt.Sprint(e.cacheHits) }},
+		{"bgpstuff_stale_answers_total", "Lookups answered with stale data because the API failed.", "counter",
+			func(e *endpointMetrics) string { return fmt.Sprint(e.stale) }},
+		{"bgpstuff_cache_evictions_total", "Responses evicted from the full cache.", "counter",
+			func(e *endpointMetrics) string { return fmt.Sprint(e.evictions) }},
+	}
+	for _, s := range series {
+		fmt.Fprintf(w, "# HELP %s %s\n", s.name, s.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", s.name, s.kind)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{endpoint=%q} %s\n", s.name, name, s.value(m.endpoints[name]))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package bgpstuff
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestDurationExcludesQueueing(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI, WithLimiter(slowLimiter{d: 200 * time.Millisecond}))
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	c.metrics.mu.Lock()
+	took := c.metrics.endpoints["origin"].duration
+	c.metrics.mu.Unlock()
+	if took <= 0 || took >= 200*time.Millisecond {
+		t.Errorf("Got request duration: %v, Want the request alone, without the 200ms wait", took)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI, WithNegativeCacheTTL(time.Minute))
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetRoute("19.1.1.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	c.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE bgpstuff_requests_total counter\n",
+		`bgpstuff_requests_total{endpoint="route"} 1` + "\n",
+		`bgpstuff_cache_hits_total{endpoint="route"} 1` + "\n",
+		`bgpstuff_request_failures_total{endpoint="route"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI, WithCacheTTL(time.Minute))
+	for _, ip := range []string{"1.1.1.1", "1.1.1.1", "8.8.8.8"} {
+		if _, err := c.GetOrigin(ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.GetRoute("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.Stats()
+	if stats.Requests != 3 || stats.Failures != 0 || stats.Since.IsZero() {
+		t.Errorf("Got: %+v, Want 3 requests and no failures", stats)
+	}
+	origin := stats.Endpoints["origin"]
+	if origin.Requests != 2 || origin.CacheHits != 1 {
+		t.Errorf("Got origin: %+v, Want 2 requests and 1 cache hit", origin)
+	}
+}
@@ -1,69 +1,58 @@
 package bgpstuff
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
-	"time"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/api"
 )
 
 type response struct {
 	Data data `json:"Response"`
+
+	// timing is how long the request for the response took.
+	timing timing
 }
 
 // data is the struct received on each successul query.
-// Copied directly from https://github.com/mellowdrifter/bgpstuff.netv2/blob/master/pkg/models/models.go
-type data struct {
-	Action    string      // What action is being performed?
-	Route     string      // /route response
-	ASPath    []string    `json:"ASPath"` // aspath response
-	ASSet     []string    `json:"ASSet"`
-	Origin    int         `json:"Origin,string"`
-	ROA       string      `json:"ROA"`    // /roa response
-	ASName    string      `json:"ASName"` // /asname response
-	ASLocale  string      // /asname locale
-	ASNames   []ASNumName `json:"ASNames"` // /asnames response
-	Invalids  []Invalids  // /invalids response
-	Sourced   Sourced     // /sourced response
-	Location  Location    // /whereami response
-	Totals    Totals      // /totals response
-	IP        string      // IP address being queried
-	Exists    bool        // Specifies if there was an actual reply
-	CacheTime time.Time   // If set, this is how old the entry is in the cache
+type data = api.Data
+
+// RouteResult is the result of a route lookup.
+type RouteResult struct {
+	// Prefix is the covering route, if there is one.
+	Prefix netip.Prefix
+	// Exists is false if there is no route at all.
+	Exists bool
+	// Default is true if the only covering route is a default route.
+	Default bool
 }
 
 // Sourced contains the amount of IPv4 and IPv6 prefixes.
 // As well as the prefixes.
-type Sourced struct {
-	Ipv4, Ipv6 int
-	Prefixes   []string
-}
+type Sourced = api.Sourced
 
 // Totals contains the amount of IPv4 and IPv6 prefixes in the RIB.
 // Also the unix timestamp
-type Totals struct {
-	Ipv4, Ipv6 int
-	Time       uint64
-}
+type Totals = api.Totals
 
 // Location contains the coordinates and map of the ingress location.
-type Location struct {
-	Lat, Long     string
-	City, Country string
-	Map           string // a base64 encoded png
-}
+type Location = api.Location
 
 // Invalids contains all the ROA invalids prefixes originated by an ASN.
-type Invalids struct {
-	ASN      int `json:"ASN,string"`
-	Prefixes []string
-}
+type Invalids = api.Invalids
 
 // ASNumName contains an AS number, name, and locale.
-type ASNumName struct {
-	ASN      uint32
-	ASName   string
-	ASLocale string
-}
+type ASNumName = api.ASNumName
 
 // decodeJSON will populate a response struct with the body of the reply from the server.
 // Returns an error if it cannot unmarshal.
@@ -71,3 +60,284 @@ func (res *response) decodeJSON(r io.Reader) error {
 	e := json.NewDecoder(r)
 	return e.Decode(res)
 }
+
+// decodeInvalids reads an /invalids response from r one entry at a time,
+// parsing prefixes in parallel as it goes rather than decoding the whole
+// table first. Entries for ASNs that keep returns false for are skipped.
+func decodeInvalids(r io.Reader, keep func(asn int) bool) (map[int][]*net.IPNet, error) {
+	dec := json.NewDecoder(r)
+	if ok, err := enterInvalids(dec); !ok {
+		return make(map[int][]*net.IPNet), err
+	}
+
+	pool := newParsePool()
+	for dec.More() && !pool.failed() {
+		var v Invalids
+		if err := dec.Decode(&v); err != nil {
+			pool.wait()
+			return nil, err
+		}
+		if keep != nil && !keep(v.ASN) {
+			continue
+		}
+		pool.add(v.ASN, v.Prefixes)
+	}
+
+	return pool.wait()
+}
+
+// decodeInvalidCounts reads an /invalids response from r and counts the
+// prefixes of each ASN without keeping them. count returns how many of an
+// entry's prefixes to count.
+func decodeInvalidCounts(r io.Reader, count func(asn int, prefixes []string) (int, error)) (map[int]int, error) {
+	dec := json.NewDecoder(r)
+	counts := make(map[int]int)
+	if ok, err := enterInvalids(dec); !ok {
+		return counts, err
+	}
+	for dec.More() {
+		var v Invalids
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		n, err := count(v.ASN, v.Prefixes)
+		if err != nil {
+			return nil, err
+		}
+		// An ASN listed more than once keeps its last entry, as it does
+		// in the full table.
+		delete(counts, v.ASN)
+		if n > 0 {
+			counts[v.ASN] = n
+		}
+	}
+	return counts, nil
+}
+
+// enterInvalids reads an /invalids response up to the first entry of the
+// list. It returns false if there are no entries to read, either because
+// the list is null or because of an error.
+func enterInvalids(dec *json.Decoder) (bool, error) {
+	if err := enterObjectKey(dec, "Response", nil); err != nil {
+		return false, err
+	}
+	checkSkipped := func(key string, value json.RawMessage) error {
+		if key != "Action" {
+			return nil
+		}
+		var action string
+		json.Unmarshal(value, &action)
+		return checkAction("invalids", action)
+	}
+	if err := enterObjectKey(dec, "Invalids", checkSkipped); err != nil {
+		return false, err
+	}
+	// A null list means there are no invalids.
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		return false, nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return false, fmt.Errorf("invalids: expected list, got %v", tok)
+	}
+	return true, nil
+}
+
+// parsePool parses the prefixes of invalids entries on every core while
+// later entries are still being decoded, as parsing hundreds of thousands
+// of prefixes is most of the work of loading the table.
+type parsePool struct {
+	jobs    chan parseJob
+	results chan parseResult
+	wg      sync.WaitGroup
+	done    chan struct{}
+	stop    chan struct{}
+
+	next     int
+	invalids map[int][]*net.IPNet
+	err      error
+}
+
+type parseJob struct {
+	seq      int
+	asn      int
+	prefixes []string
+}
+
+type parseResult struct {
+	seq  int
+	asn  int
+	nets []*net.IPNet
+	err  error
+}
+
+func newParsePool() *parsePool {
+	workers := runtime.GOMAXPROCS(0)
+	p := &parsePool{
+		jobs:     make(chan parseJob, workers*4),
+		results:  make(chan parseResult, workers*4),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		invalids: make(map[int][]*net.IPNet),
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				nets, err := parseCIDRs(job.prefixes)
+				p.results <- parseResult{seq: job.seq, asn: job.asn, nets: nets, err: err}
+			}
+		}()
+	}
+	go p.collect()
+	return p
+}
+
+// collect gathers parsed entries. An ASN listed more than once keeps its
+// last entry, as it would if the entries were parsed in order.
+func (p *parsePool) collect() {
+	defer close(p.done)
+	seqs := make(map[int]int)
+	for r := range p.results {
+		if r.err != nil {
+			if p.err == nil {
+				p.err = r.err
+				close(p.stop)
+			}
+			continue
+		}
+		if seq, ok := seqs[r.asn]; ok && seq > r.seq {
+			continue
+		}
+		seqs[r.asn] = r.seq
+		p.invalids[r.asn] = r.nets
+	}
+}
+
+func (p *parsePool) add(asn int, prefixes []string) {
+	select {
+	case p.jobs <- parseJob{seq: p.next, asn: asn, prefixes: prefixes}:
+		p.next++
+	case <-p.stop:
+	}
+}
+
+// failed reports whether an entry has failed to parse, so decoding can
+// stop early.
+func (p *parsePool) failed() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait returns the parsed table once every entry added has been parsed.
+func (p *parsePool) wait() (map[int][]*net.IPNet, error) {
+	close(p.jobs)
+	p.wg.Wait()
+	close(p.results)
+	<-p.done
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.invalids, nil
+}
+
+// parseCIDRs parses prefixes in order.
+func parseCIDRs(prefixes []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		_, ipnet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
+
+// enterObjectKey reads the opening of a JSON object from dec and skips
+// forward until the value of key is next. Fields skipped on the way are
+// passed to skipped, if it is not nil, and an error from it stops the read.
+func enterObjectKey(dec *json.Decoder, key string, skipped func(key string, value json.RawMessage) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected object containing %s, got %v", key, tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == key {
+			return nil
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+		if skipped != nil {
+			if err := skipped(tok.(string), skip); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("response has no %s field", key)
+}
+
+// limitedReader reads from r until n bytes have been read, after which it
+// returns ErrResponseTooLarge. Unlike io.LimitReader, running out of budget
+// is an error rather than a silent EOF, so truncated JSON is never decoded.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Only an error if there is actually more to read.
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n == 0 {
+			return 0, io.EOF
+		}
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// checkJSON returns ErrNonJSONResponse if res is an HTML or XML page
+// rather than JSON, going by its Content-Type or, as servers often send
+// JSON as text/plain, by the first byte of the body in br. This catches
+// error pages from proxies and CDNs whatever their status, which would
+// otherwise fail to decode with a cryptic error.
+func checkJSON(res *http.Response, br *bufio.Reader) error {
+	peek, _ := br.Peek(512)
+	trimmed := bytes.TrimSpace(peek)
+
+	contentType := res.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	html := mediaType == "text/html" || strings.HasSuffix(mediaType, "xml")
+	if !html && (len(trimmed) == 0 || trimmed[0] != '<') {
+		return nil
+	}
+
+	snippet := strings.Join(strings.Fields(string(trimmed)), " ")
+	if len(snippet) > 120 {
+		snippet = snippet[:120] + "..."
+	}
+	return fmt.Errorf("%w: status %d, %s: %q", ErrNonJSONResponse, res.StatusCode, contentType, snippet)
+}
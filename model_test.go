@@ -0,0 +1,37 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDecodeInvalidsParallel(t *testing.T) {
+	t.Parallel()
+	var body strings.Builder
+	body.WriteString(`{"Response":{"Action":"invalids","Invalids":[`)
+	for asn := 1; asn <= 2000; asn++ {
+		fmt.Fprintf(&body, `{"ASN":"%d","Prefixes":["10.%d.%d.0/24","10.%d.%d.128/25"]},`, asn, asn/256, asn%256, asn/256, asn%256)
+	}
+	// A repeated ASN keeps its last entry.
+	body.WriteString(`{"ASN":"1","Prefixes":["192.0.2.0/24"]}]}}`)
+
+	invalids, err := decodeInvalids(strings.NewReader(body.String()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalids) != 2000 {
+		t.Errorf("Got %d ASNs, Want 2000", len(invalids))
+	}
+	if got := invalids[1000]; len(got) != 2 || got[0].String() != "10.3.232.0/24" || got[1].String() != "10.3.232.128/25" {
+		t.Errorf("Got AS1000: %v, Want both prefixes in order", got)
+	}
+	if got := invalids[1]; len(got) != 1 || got[0].String() != "192.0.2.0/24" {
+		t.Errorf("Got AS1: %v, Want its last entry", got)
+	}
+
+	bad := strings.Replace(body.String(), "10.3.232.0/24", "10.3.232.0/33", 1)
+	if _, err := decodeInvalids(strings.NewReader(bad), nil); err == nil {
+		t.Error("Expected error for bad prefix, but no error returned")
+	}
+}
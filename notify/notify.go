@@ -0,0 +1,275 @@
+// Package notify sends the changes seen by Client.Subscribe to alerting
+// services. Each notifier is a bgpstuff.Notifier, to be passed to
+// bgpstuff.WithNotifier:
+//
+//	c := bgpstuff.NewBGPClient(false,
+//		bgpstuff.WithNotifier(notify.NewSlack(slackURL)),
+//		bgpstuff.WithNotifier(notify.NewPagerDuty(routingKey)),
+//		bgpstuff.WithNotifyErrorHandler(func(n bgpstuff.Notifier, ev bgpstuff.Event, err error) {
+//			log.Printf("%T: %v", n, err)
+//		}))
+//	events, err := c.Subscribe(ctx, bgpstuff.TopicInvalids)
+//
+// The events must still be read from the subscription channel. Notifiers
+// return the errors they get sending, such as a rejected URL or routing
+// key, and without WithNotifyErrorHandler those are lost.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+const (
+	defaultMaxLines = 20
+	pagerDutyURL    = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// Option configures a notifier.
+type Option func(*config)
+
+type config struct {
+	client   *http.Client
+	endpoint string
+	severity string
+	maxLines int
+}
+
+func newConfig(endpoint string, opts []Option) config {
+	cfg := config{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		endpoint: endpoint,
+		severity: "warning",
+		maxLines: defaultMaxLines,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithHTTPClient sets the client notifications are sent with. The default
+// has a 10 second timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(cfg *config) {
+		cfg.client = hc
+	}
+}
+
+// WithEndpoint sets the URL notifications are sent to. It is mostly of
+// use to send PagerDuty events through a proxy.
+func WithEndpoint(url string) Option {
+	return func(cfg *config) {
+		cfg.endpoint = url
+	}
+}
+
+// WithSeverity sets the severity of PagerDuty alerts: "critical", "error",
+// "warning" or "info". The default is "warning".
+func WithSeverity(severity string) Option {
+	return func(cfg *config) {
+		cfg.severity = severity
+	}
+}
+
+// WithMaxLines sets how many prefixes a Slack or PagerDuty message lists
+// before summarising the rest. The default is 20.
+func WithMaxLines(n int) Option {
+	return func(cfg *config) {
+		cfg.maxLines = n
+	}
+}
+
+// Webhook POSTs each event as JSON to a URL.
+type Webhook struct {
+	cfg config
+}
+
+var _ bgpstuff.Notifier = (*Webhook)(nil)
+
+// NewWebhook returns a Webhook posting to url.
+func NewWebhook(url string, opts ...Option) *Webhook {
+	return &Webhook{cfg: newConfig(url, opts)}
+}
+
+// WebhookPayload is the body a Webhook posts. Prefixes are keyed by their
+// origin ASN.
+type WebhookPayload struct {
+	Topic   string              `json:"topic"`
+	Time    time.Time           `json:"time"`
+	IPv4    int                 `json:"ipv4,omitempty"`
+	IPv6    int                 `json:"ipv6,omitempty"`
+	Added   map[string][]string `json:"added,omitempty"`
+	Removed map[string][]string `json:"removed,omitempty"`
+}
+
+// Notify posts ev.
+func (w *Webhook) Notify(ctx context.Context, ev bgpstuff.Event) error {
+	return post(ctx, w.cfg.client, w.cfg.endpoint, WebhookPayload{
+		Topic:   ev.Topic.String(),
+		Time:    ev.Time,
+		IPv4:    ev.Totals.Ipv4,
+		IPv6:    ev.Totals.Ipv6,
+		Added:   prefixStrings(ev.Added),
+		Removed: prefixStrings(ev.Removed),
+	})
+}
+
+// Slack posts a message for each event to a Slack incoming webhook.
+type Slack struct {
+	cfg config
+}
+
+var _ bgpstuff.Notifier = (*Slack)(nil)
+
+// NewSlack returns a Slack notifier posting to the incoming webhook url.
+func NewSlack(url string, opts ...Option) *Slack {
+	return &Slack{cfg: newConfig(url, opts)}
+}
+
+// Notify posts a message describing ev.
+func (s *Slack) Notify(ctx context.Context, ev bgpstuff.Event) error {
+	text := summary(ev)
+	if details := changes(ev, s.cfg.maxLines); details != "" {
+		text += "\n```\n" + details + "```"
+	}
+	return post(ctx, s.cfg.client, s.cfg.endpoint, struct {
+		Text string `json:"text"`
+	}{text})
+}
+
+// PagerDuty triggers a PagerDuty alert when new invalids appear. Other
+// events are ignored, as they need no one woken up.
+type PagerDuty struct {
+	routingKey string
+	cfg        config
+}
+
+var _ bgpstuff.Notifier = (*PagerDuty)(nil)
+
+// NewPagerDuty returns a PagerDuty notifier sending Events API v2 alerts
+// with the integration's routing key.
+func NewPagerDuty(routingKey string, opts ...Option) *PagerDuty {
+	return &PagerDuty{routingKey: routingKey, cfg: newConfig(pagerDutyURL, opts)}
+}
+
+// Notify triggers an alert if ev has new invalids.
+func (p *PagerDuty) Notify(ctx context.Context, ev bgpstuff.Event) error {
+	if ev.Topic != bgpstuff.TopicInvalids || len(ev.Added) == 0 {
+		return nil
+	}
+	type payload struct {
+		Summary       string            `json:"summary"`
+		Source        string            `json:"source"`
+		Severity      string            `json:"severity"`
+		Timestamp     string            `json:"timestamp"`
+		CustomDetails map[string]string `json:"custom_details"`
+	}
+	return post(ctx, p.cfg.client, p.cfg.endpoint, struct {
+		RoutingKey  string  `json:"routing_key"`
+		EventAction string  `json:"event_action"`
+		Payload     payload `json:"payload"`
+	}{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		Payload: payload{
+			Summary:       summary(ev),
+			Source:        "bgpstuff.net",
+			Severity:      p.cfg.severity,
+			Timestamp:     ev.Time.Format(time.RFC3339),
+			CustomDetails: map[string]string{"changes": changes(ev, p.cfg.maxLines)},
+		},
+	})
+}
+
+// summary returns a one line description of ev.
+func summary(ev bgpstuff.Event) string {
+	switch ev.Topic {
+	case bgpstuff.TopicTotals:
+		return fmt.Sprintf("BGP table size is now %d IPv4 and %d IPv6 prefixes", ev.Totals.Ipv4, ev.Totals.Ipv6)
+	case bgpstuff.TopicInvalids:
+		return fmt.Sprintf("ROA invalids changed: %d new, %d no longer invalid", count(ev.Added), count(ev.Removed))
+	}
+	return ev.Topic.String() + " changed"
+}
+
+// changes lists the prefixes added and removed in ev, one per line, up to
+// max lines.
+func changes(ev bgpstuff.Event, max int) string {
+	lines := append(prefixLines(ev.Added, "new invalid"), prefixLines(ev.Removed, "no longer invalid")...)
+	var b bytes.Buffer
+	for i, l := range lines {
+		if max > 0 && i == max {
+			fmt.Fprintf(&b, "and %d more\n", len(lines)-max)
+			break
+		}
+		b.WriteString(l + "\n")
+	}
+	return b.String()
+}
+
+func prefixLines(prefixes map[int][]*net.IPNet, verb string) []string {
+	asns := make([]int, 0, len(prefixes))
+	for asn := range prefixes {
+		asns = append(asns, asn)
+	}
+	sort.Ints(asns)
+	var lines []string
+	for _, asn := range asns {
+		for _, p := range prefixes[asn] {
+			lines = append(lines, fmt.Sprintf("%s: %s from AS%d", verb, p, asn))
+		}
+	}
+	return lines
+}
+
+func count(prefixes map[int][]*net.IPNet) int {
+	n := 0
+	for _, p := range prefixes {
+		n += len(p)
+	}
+	return n
+}
+
+func prefixStrings(prefixes map[int][]*net.IPNet) map[string][]string {
+	if len(prefixes) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(prefixes))
+	for asn, ps := range prefixes {
+		for _, p := range ps {
+			out[strconv.Itoa(asn)] = append(out[strconv.Itoa(asn)], p.String())
+		}
+	}
+	return out
+}
+
+func post(ctx context.Context, hc *http.Client, url string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("notify %s: received status: %s (%d)", req.URL.Host, http.StatusText(res.StatusCode), res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net"
+	"github.com/mellowdrifter/go-bgpstuff.net/notify"
+)
+
+// receiver records the bodies posted to it.
+func receiver(t *testing.T, status int) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func invalidsEvent(t *testing.T) bgpstuff.Event {
+	t.Helper()
+	_, added, err := net.ParseCIDR("23.1.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bgpstuff.Event{
+		Topic: bgpstuff.TopicInvalids,
+		Time:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Added: map[int][]*net.IPNet{64501: {added}},
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+	srv, bodies := receiver(t, http.StatusNoContent)
+	if err := notify.NewWebhook(srv.URL).Notify(context.Background(), invalidsEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+	got := *bodies
+	if len(got) != 1 || got[0]["topic"] != "invalids" {
+		t.Fatalf("Got: %v", got)
+	}
+	added := got[0]["added"].(map[string]interface{})["64501"].([]interface{})
+	if len(added) != 1 || added[0] != "23.1.0.0/16" {
+		t.Errorf("Got added: %v, Want 23.1.0.0/16 from 64501", added)
+	}
+
+	srv, _ = receiver(t, http.StatusInternalServerError)
+	if err := notify.NewWebhook(srv.URL).Notify(context.Background(), invalidsEvent(t)); err == nil {
+		t.Error("Expected error for status 500, but no error returned")
+	}
+}
+
+func TestSlack(t *testing.T) {
+	t.Parallel()
+	srv, bodies := receiver(t, http.StatusOK)
+	if err := notify.NewSlack(srv.URL).Notify(context.Background(), invalidsEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := (*bodies)[0]["text"].(string)
+	if !strings.HasPrefix(text, "ROA invalids changed: 1 new, 0 no longer invalid") ||
+		!strings.Contains(text, "new invalid: 23.1.0.0/16 from AS64501") {
+		t.Errorf("Got text: %q", text)
+	}
+}
+
+func TestPagerDuty(t *testing.T) {
+	t.Parallel()
+	srv, bodies := receiver(t, http.StatusAccepted)
+	pd := notify.NewPagerDuty("key", notify.WithEndpoint(srv.URL), notify.WithSeverity("critical"))
+
+	// Only new invalids trigger an alert.
+	if err := pd.Notify(context.Background(), bgpstuff.Event{Topic: bgpstuff.TopicTotals}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pd.Notify(context.Background(), invalidsEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+	got := *bodies
+	if len(got) != 1 {
+		t.Fatalf("Got %d alerts, Want 1", len(got))
+	}
+	payload := got[0]["payload"].(map[string]interface{})
+	if got[0]["routing_key"] != "key" || got[0]["event_action"] != "trigger" || payload["severity"] != "critical" {
+		t.Errorf("Got: %v", got[0])
+	}
+}
@@ -0,0 +1,243 @@
+package bgpstuff
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Option configures a Client. Options are passed to NewBGPClient.
+type Option func(*Client)
+
+// WithMaxResponseBytes limits how many bytes of a response body the client
+// will read. Responses larger than n fail with ErrResponseTooLarge instead of
+// being decoded. A value of zero or less means no limit, which is the default.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *Client) {
+		c.maxResponseBytes = n
+	}
+}
+
+// WithPollInterval sets how often Subscribe polls the API for changes.
+// The default is one minute.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.pollInterval = d
+	}
+}
+
+// WithNotifier has Subscribe pass each change it sees to n as well as
+// sending it on the channel. It may be given more than once to add several
+// notifiers. Package notify has notifiers for webhooks, Slack and PagerDuty.
+func WithNotifier(n Notifier) Option {
+	return func(c *Client) {
+		c.notifiers = append(c.notifiers, n)
+	}
+}
+
+// WithNotifyErrorHandler has handle called with each notifier that fails
+// to deliver an event, along with the event and the error, such as a
+// rejected webhook URL or routing key. Without it those failures are lost.
+// It is called from the goroutine polling the API.
+func WithNotifyErrorHandler(handle func(Notifier, Event, error)) Option {
+	return func(c *Client) {
+		c.notifyErr = handle
+	}
+}
+
+// WithInvalidCountsOnly makes GetInvalids keep only how many invalid
+// prefixes each ASN originates, not the prefixes themselves, for agents
+// short of memory that only need InvalidCount or TopInvalidASNs. GetInvalid
+// then downloads the prefixes of an ASN when asked, and InvalidTable,
+// GetInvalidOrigins and the deprecated Invalids field stay empty.
+func WithInvalidCountsOnly() Option {
+	return func(c *Client) {
+		c.invalidCountsOnly = true
+	}
+}
+
+// WithSeparateLookups makes Report, ReportJSON and Query fetch the route
+// and origin of an IP from their own endpoints. By default they are taken
+// from the /aspath answer, which carries both, saving two requests per IP.
+func WithSeparateLookups() Option {
+	return func(c *Client) {
+		c.separateLookups = true
+	}
+}
+
+// WithStaleIfError makes lookups return the last answer fetched for the same
+// query, if it is no older than maxStale, when the API cannot be reached:
+// the request failed in transport, timed out or got a 5xx status. Answers
+// kept by WithStore are used if there is none in memory. Answers served
+// this way have Meta.Stale set. Other errors, such as a 4xx status, are
+// always returned.
+func WithStaleIfError(maxStale time.Duration) Option {
+	return func(c *Client) {
+		c.staleIfError = maxStale
+	}
+}
+
+// WithCoalesce makes identical lookups share a single API request. Lookups
+// made while a request is in flight, or up to window after it completes,
+// get the same answer. This is separate from WithStaleIfError, and is meant
+// for bursts of the same query such as those from flow collectors.
+func WithCoalesce(window time.Duration) Option {
+	return func(c *Client) {
+		if window > 0 {
+			c.coalesce = newCoalescer(window)
+		}
+	}
+}
+
+// WithRequestHook adds a function that is called with every request just
+// before it is sent, for example to add headers or start a tracing span.
+// Hooks run in the order they were added.
+func WithRequestHook(hook func(*http.Request)) Option {
+	return func(c *Client) {
+		c.requestHooks = append(c.requestHooks, hook)
+	}
+}
+
+// WithResponseHook adds a function that is called with every response and
+// how long the request took, before the body is read. Hooks must not read
+// or close the body. Hooks run in the order they were added.
+func WithResponseHook(hook func(*http.Response, time.Duration)) Option {
+	return func(c *Client) {
+		c.responseHooks = append(c.responseHooks, hook)
+	}
+}
+
+// WithCacheTTL caches answers for ttl, so repeating a lookup within that
+// time does not make another request. Caching is off by default.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithCacheSize bounds the cache to n responses. Once full, the least
+// recently used response is evicted to make room, and the eviction is
+// counted in the client's metrics. The default is 10000; zero or less
+// removes the bound.
+func WithCacheSize(n int) Option {
+	return func(c *Client) {
+		c.cacheSize = n
+	}
+}
+
+// WithNegativeCacheTTL sets how long answers saying there was nothing to
+// find, such as no route for an IP or no name for an ASN, are cached. This
+// keeps repeated lookups of unrouted space from using up the rate limit.
+// Negative caching is off by default, so a newly announced route is seen on
+// the next lookup; zero or less turns it back off.
+func WithNegativeCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithAllowPrivate lets the client look up private, reserved and
+// documentation IPs and ASNs, which it refuses by default. It is meant for
+// self-hosted servers fed from lab route collectors that carry such space.
+func WithAllowPrivate() Option {
+	return func(c *Client) {
+		c.allowPrivate = true
+	}
+}
+
+// CallOption configures a single lookup. CallOptions are passed as the last
+// arguments to the lookup methods.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	ctx      context.Context
+	meta     *Meta
+	noCache  bool
+	timeout  time.Duration
+	priority Priority
+
+	// result is the Meta of the last request made for the call, whether or
+	// not the caller asked for it.
+	result Meta
+	// timing is filled in by the last request made for the call.
+	timing timing
+	// sent is when the last request made for the call was sent, once it
+	// was through the rate limiter and had a free slot.
+	sent time.Time
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	call := &callOptions{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(call)
+	}
+	return call
+}
+
+// bulkCallOptions is newCallOptions for calls fetching whole tables, which
+// run at PriorityLow unless opts say otherwise.
+func bulkCallOptions(opts []CallOption) *callOptions {
+	call := newCallOptions(nil)
+	call.priority = PriorityLow
+	for _, opt := range opts {
+		opt(call)
+	}
+	return call
+}
+
+// requestTime returns how long it has been since the last request made for
+// the call was sent, leaving out any time it spent queued for the rate
+// limiter or a free slot. It is 0 if the request was never sent.
+func (call *callOptions) requestTime() time.Duration {
+	if call.sent.IsZero() {
+		return 0
+	}
+	return time.Since(call.sent)
+}
+
+// setMeta records m, and passes it on to the caller if they asked for it
+// with WithMeta.
+func (call *callOptions) setMeta(m Meta) {
+	call.result = m
+	if call.meta != nil {
+		*call.meta = m
+	}
+}
+
+// WithMeta fills in m with details of where the answer to the call came from.
+func WithMeta(m *Meta) CallOption {
+	return func(call *callOptions) {
+		call.meta = m
+	}
+}
+
+// WithContext makes the call use ctx, so it can be cancelled or given a
+// deadline. This covers the wait for the rate limiter as well as the request.
+func WithContext(ctx context.Context) CallOption {
+	return func(call *callOptions) {
+		call.ctx = ctx
+	}
+}
+
+// CallContext returns the context set with WithContext among opts, or
+// context.Background if there is none. It lets other Lookuper
+// implementations honour the same call options as the client.
+func CallContext(opts ...CallOption) context.Context {
+	return newCallOptions(opts).ctx
+}
+
+// NoCache makes the call go to the API even if the client has a cached
+// answer, and stops a stale answer being returned if the request fails.
+// The fresh answer is still cached for later calls.
+func NoCache() CallOption {
+	return func(call *callOptions) {
+		call.noCache = true
+	}
+}
+
+// WithTimeoutOpt overrides the client's request timeout for this call.
+func WithTimeoutOpt(d time.Duration) CallOption {
+	return func(call *callOptions) {
+		call.timeout = d
+	}
+}
@@ -0,0 +1,93 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxResponseBytes(t *testing.T) {
+	t.Parallel()
+	body := `{"Response":{"Action":"totals","Totals":{"Ipv4":900000,"Ipv6":150000}}}`
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}
+	tests := []struct {
+		name    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "unlimited"},
+		{name: "exact", limit: int64(len(body))},
+		{name: "too small", limit: 10, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, handler, WithMaxResponseBytes(tc.limit))
+			_, _, err := c.GetTotals()
+			if tc.wantErr && !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("Expected ErrResponseTooLarge, got: %v", err)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("No error expected, but got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	var gotHeader string
+	var gotStatus int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Test")
+		fakeAPI(w, r)
+	},
+		WithRequestHook(func(r *http.Request) { r.Header.Set("X-Test", "hooked") }),
+		WithResponseHook(func(r *http.Response, _ time.Duration) { gotStatus = r.StatusCode }),
+	)
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if gotHeader != "hooked" {
+		t.Errorf("Got header: %q, Want: hooked", gotHeader)
+	}
+	if gotStatus != http.StatusOK {
+		t.Errorf("Got status: %d, Want: %d", gotStatus, http.StatusOK)
+	}
+}
+
+func TestCallOptions(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/origin/8.8.8.8" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		fakeAPI(w, r)
+	}, WithCacheTTL(time.Hour))
+
+	for _, opts := range [][]CallOption{nil, nil, {NoCache()}} {
+		if _, err := c.GetOrigin("1.1.1.1", opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Got %d requests, Want 2", got)
+	}
+
+	if _, err := c.GetOrigin("8.8.8.8", WithTimeoutOpt(10*time.Millisecond)); err == nil {
+		t.Error("Expected timeout error, but no error returned")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetOrigin("8.8.4.4", WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package bgpstuff
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority orders requests waiting for the rate limiter. When several are
+// waiting, the highest priority one is let through first, so interactive
+// lookups don't queue behind bulk refreshes.
+type Priority int
+
+// Request priorities. Lookups are PriorityNormal unless set otherwise;
+// GetASNames, GetInvalids and Subscribe default to PriorityLow.
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+// WithPriority sets the priority of the call's requests.
+func WithPriority(p Priority) CallOption {
+	return func(call *callOptions) {
+		call.priority = p
+	}
+}
+
+// scheduler hands out turns to wait on the rate limiter, one at a time,
+// highest priority first and in arrival order within a priority.
+type scheduler struct {
+	mu      sync.Mutex
+	busy    bool
+	waiting [3][]chan struct{}
+}
+
+func queueIndex(p Priority) int {
+	switch {
+	case p >= PriorityHigh:
+		return 0
+	case p <= PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// acquire blocks until it is the caller's turn, or ctx is done.
+func (s *scheduler) acquire(ctx context.Context, p Priority) error {
+	s.mu.Lock()
+	if !s.busy {
+		s.busy = true
+		s.mu.Unlock()
+		return nil
+	}
+	turn := make(chan struct{})
+	i := queueIndex(p)
+	s.waiting[i] = append(s.waiting[i], turn)
+	s.mu.Unlock()
+
+	select {
+	case <-turn:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	for j, t := range s.waiting[i] {
+		if t == turn {
+			s.waiting[i] = append(s.waiting[i][:j], s.waiting[i][j+1:]...)
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	s.mu.Unlock()
+	// The turn was handed over as ctx finished, so pass it on.
+	s.release()
+	return ctx.Err()
+}
+
+// release hands the turn to the next waiter.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, queue := range s.waiting {
+		if len(queue) > 0 {
+			close(queue[0])
+			s.waiting[i] = queue[1:]
+			return
+		}
+	}
+	s.busy = false
+}
@@ -0,0 +1,66 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerPriority(t *testing.T) {
+	t.Parallel()
+	var s scheduler
+	ctx := context.Background()
+	if err := s.acquire(ctx, PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	queued := func(n int) {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			s.mu.Lock()
+			got := len(s.waiting[0]) + len(s.waiting[1]) + len(s.waiting[2])
+			s.mu.Unlock()
+			if got == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Waiters never reached %d", n)
+	}
+
+	order := make(chan Priority, 3)
+	start := func(p Priority) {
+		go func() {
+			if err := s.acquire(ctx, p); err != nil {
+				t.Error(err)
+				return
+			}
+			order <- p
+		}()
+	}
+	start(PriorityLow)
+	queued(1)
+	start(PriorityNormal)
+	queued(2)
+	start(PriorityHigh)
+	queued(3)
+
+	// A waiter that gives up leaves the queue without taking a turn.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.acquire(cancelled, PriorityHigh); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+
+	for _, want := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		s.release()
+		if got := <-order; got != want {
+			t.Errorf("Got priority %d, Want %d", got, want)
+		}
+	}
+	s.release()
+	if s.busy {
+		t.Error("Scheduler still busy after the last release")
+	}
+}
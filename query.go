@@ -0,0 +1,91 @@
+package bgpstuff
+
+import (
+	"net"
+	"strings"
+)
+
+// QueryKind is the kind of argument Query was given.
+type QueryKind int
+
+// Kinds of Query argument.
+const (
+	QueryIP QueryKind = iota
+	QueryPrefix
+	QueryASN
+)
+
+func (k QueryKind) String() string {
+	switch k {
+	case QueryIP:
+		return "ip"
+	case QueryPrefix:
+		return "prefix"
+	case QueryASN:
+		return "asn"
+	}
+	return "unknown"
+}
+
+// QueryResult is the answer to Query. IP is set for IP and prefix queries,
+// and AS for ASN queries.
+type QueryResult struct {
+	Kind QueryKind
+	IP   *IPReport
+	AS   *ASReport
+}
+
+// ASReport holds what the client knows about an AS.
+type ASReport struct {
+	ASN      ASN
+	Name     string
+	Prefixes []*net.IPNet
+	IPv4     int
+	IPv6     int
+}
+
+// Query works out whether arg is an IP address, a prefix or an ASN and runs
+// the matching lookups. IPs and prefixes get a full report, using the
+// network address of a prefix; ASNs get their name and sourced prefixes.
+func (c *Client) Query(arg string, opts ...CallOption) (*QueryResult, error) {
+	arg = strings.TrimSpace(arg)
+
+	if _, ok := normalizeIP(arg); ok {
+		report, err := c.lookup(arg, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResult{Kind: QueryIP, IP: report}, nil
+	}
+
+	if _, prefix, err := net.ParseCIDR(arg); err == nil {
+		report, err := c.lookup(prefix.IP.String(), opts)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResult{Kind: QueryPrefix, IP: report}, nil
+	}
+
+	asn, err := ParseASN(arg)
+	if err != nil {
+		return nil, lookupError("query", arg, err)
+	}
+	name, err := c.GetASName(int(asn), opts...)
+	if err != nil {
+		return nil, err
+	}
+	prefixes, v4, v6, err := c.GetSourced(int(asn), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResult{
+		Kind: QueryASN,
+		AS: &ASReport{
+			ASN:      asn,
+			Name:     name,
+			Prefixes: prefixes,
+			IPv4:     v4,
+			IPv6:     v6,
+		},
+	}, nil
+}
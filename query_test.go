@@ -0,0 +1,57 @@
+package bgpstuff
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sourced/13335" {
+			fmt.Fprint(w, `{"Response":{"Sourced":{"Ipv4":1,"Ipv6":0,"Prefixes":["1.1.1.0/24"]}}}`)
+			return
+		}
+		fakeAPI(w, r)
+	})
+	tests := []struct {
+		arg      string
+		wantKind QueryKind
+		wantErr  bool
+	}{
+		{arg: "1.1.1.1", wantKind: QueryIP},
+		{arg: "1.1.1.1/32", wantKind: QueryPrefix},
+		{arg: "AS13335", wantKind: QueryASN},
+		{arg: "13335", wantKind: QueryASN},
+		{arg: "foo", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.arg, func(t *testing.T) {
+			got, err := c.Query(tc.arg)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidASN) {
+					t.Errorf("Expected ErrInvalidASN, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tc.wantKind {
+				t.Errorf("Got kind: %s, Want: %s", got.Kind, tc.wantKind)
+			}
+			switch got.Kind {
+			case QueryIP, QueryPrefix:
+				if got.IP.Origin != 13335 {
+					t.Errorf("Got origin: %d, Want: 13335", got.IP.Origin)
+				}
+			case QueryASN:
+				if got.AS.Name != "CLOUDFLARENET" || len(got.AS.Prefixes) != 1 {
+					t.Errorf("Got: %+v, Want CLOUDFLARENET with one prefix", got.AS)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package bgpstuff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// IPReport holds everything the client knows about an IP address.
+type IPReport struct {
+	IP     string `json:"ip"`
+	Route  string `json:"route,omitempty"`
+	Origin int    `json:"origin,omitempty"`
+	ASName string `json:"as_name,omitempty"`
+	ASPath []int  `json:"as_path,omitempty"`
+	ASSet  []int  `json:"as_set,omitempty"`
+	ROA    string `json:"roa,omitempty"`
+}
+
+// lookup runs every lookup needed for a report on ip. The /aspath answer
+// carries the route and origin as well as the path, so unless the client
+// was created WithSeparateLookups it stands in for the /route and /origin
+// lookups. If a server leaves them out of a path it sends, they are
+// fetched separately after all.
+func (c *Client) lookup(ip string, opts []CallOption) (*IPReport, error) {
+	if c.separateLookups {
+		return c.lookupEach(ip, opts)
+	}
+	p, ok := c.validIP(ip)
+	if !ok {
+		return nil, lookupError("aspath", ip, ErrInvalidIP)
+	}
+	resp, err := c.getRequest(newCallOptions(opts), "aspath", p)
+	if err != nil {
+		return nil, lookupError("aspath", ip, err)
+	}
+	path, set, err := getASPathFromResponse(resp)
+	if err != nil {
+		return nil, lookupError("aspath", ip, err)
+	}
+	if len(path) > 0 && (resp.Data.Route == "" || resp.Data.Origin == 0) {
+		return c.lookupEach(ip, opts)
+	}
+	route, err := parseRoute(resp.Data.Route, p)
+	if err != nil {
+		return nil, lookupError("aspath", ip, err)
+	}
+	report := &IPReport{IP: ip}
+	// Nothing else to find if there is no route.
+	if !route.Exists || route.Default {
+		return report, nil
+	}
+	report.Route = route.Prefix.String()
+	report.Origin = resp.Data.Origin
+	report.ASPath, report.ASSet = path, set
+
+	if report.ROA, err = c.GetROA(ip, opts...); err != nil {
+		return nil, err
+	}
+	if report.Origin != 0 {
+		if report.ASName, err = c.GetASName(report.Origin, opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// lookupEach is lookup with every part fetched from its own endpoint.
+func (c *Client) lookupEach(ip string, opts []CallOption) (*IPReport, error) {
+	route, err := c.GetRoute(ip, opts...)
+	if err != nil {
+		return nil, err
+	}
+	report := &IPReport{IP: ip}
+	// Nothing else to find if there is no route.
+	if route == nil {
+		return report, nil
+	}
+	report.Route = route.String()
+
+	if report.Origin, err = c.GetOrigin(ip, opts...); err != nil {
+		return nil, err
+	}
+	if report.ASPath, report.ASSet, err = c.GetASPath(ip, opts...); err != nil {
+		return nil, err
+	}
+	if report.ROA, err = c.GetROA(ip, opts...); err != nil {
+		return nil, err
+	}
+	if report.Origin != 0 {
+		if report.ASName, err = c.GetASName(report.Origin, opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// Report looks up the route, origin, AS path, ROA status and origin AS name
+// for ip and renders them as a looking glass style text report.
+func (c *Client) Report(ip string, opts ...CallOption) (string, error) {
+	report, err := c.lookup(ip, opts)
+	if err != nil {
+		return "", err
+	}
+	return report.String(), nil
+}
+
+// ReportJSON is the same as Report, but returns the report as JSON.
+func (c *Client) ReportJSON(ip string, opts ...CallOption) ([]byte, error) {
+	report, err := c.lookup(ip, opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(report)
+}
+
+// WriteWhois looks up ip and writes the answer to w as a whois style block
+// of "key: value" lines, as NOC scripts written against Team Cymru's whois
+// service expect to parse. Comment lines start with "%".
+func (c *Client) WriteWhois(w io.Writer, ip string, opts ...CallOption) error {
+	report, err := c.lookup(ip, opts)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, report.Whois())
+	return err
+}
+
+// Whois renders the report as a whois style block.
+func (r *IPReport) Whois() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%% bgpstuff.net whois for %s\n\n", r.IP)
+	if r.Route == "" {
+		fmt.Fprintf(&b, "%% No route found for %s\n", r.IP)
+		return b.String()
+	}
+	field := func(key, value string) {
+		fmt.Fprintf(&b, "%-11s %s\n", key+":", value)
+	}
+	field("ip", r.IP)
+	field("route", r.Route)
+	field("origin", fmt.Sprintf("AS%d", r.Origin))
+	if r.ASName != "" {
+		field("as-name", r.ASName)
+	}
+	field("as-path", joinASNs(r.ASPath))
+	if len(r.ASSet) > 0 {
+		field("as-set", joinASNs(r.ASSet))
+	}
+	if r.ROA != "" {
+		field("roa-status", r.ROA)
+	}
+	field("source", "BGPSTUFF")
+	return b.String()
+}
+
+func (r *IPReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "IP:      %s\n", r.IP)
+	if r.Route == "" {
+		b.WriteString("Route:   none\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Route:   %s\n", r.Route)
+	if r.ASName != "" {
+		fmt.Fprintf(&b, "Origin:  AS%d (%s)\n", r.Origin, r.ASName)
+	} else {
+		fmt.Fprintf(&b, "Origin:  AS%d\n", r.Origin)
+	}
+	fmt.Fprintf(&b, "AS path: %s\n", joinASNs(r.ASPath))
+	if len(r.ASSet) > 0 {
+		fmt.Fprintf(&b, "AS set:  {%s}\n", joinASNs(r.ASSet))
+	}
+	if r.ROA != "" {
+		fmt.Fprintf(&b, "ROA:     %s\n", r.ROA)
+	}
+	return b.String()
+}
+
+func joinASNs(asns []int) string {
+	parts := make([]string, 0, len(asns))
+	for _, asn := range asns {
+		parts = append(parts, fmt.Sprint(asn))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,121 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+	tests := []struct {
+		ip   string
+		want string
+		// prefix is set when only the start of the report is checked.
+		prefix bool
+	}{
+		{
+			ip: "1.1.1.1",
+			want: "IP:      1.1.1.1\n" +
+				"Route:   1.1.1.0/24\n" +
+				"Origin:  AS13335 (CLOUDFLARENET)\n" +
+				"AS path: 174 13335\n" +
+				"ROA:     VALID\n",
+		},
+		{ip: "19.1.1.1", want: "IP:      19.1.1.1\nRoute:   none\n"},
+		// Prefixes and bracketed or zoned addresses are shown as given.
+		{ip: "19.1.1.0/24", want: "IP:      19.1.1.0/24\n", prefix: true},
+		{ip: "[2606:4700::1111]", want: "IP:      [2606:4700::1111]\n", prefix: true},
+		{ip: "2606:4700::1111%eth0", want: "IP:      2606:4700::1111%eth0\n", prefix: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ip, func(t *testing.T) {
+			got, err := c.Report(tc.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.prefix && !strings.HasPrefix(got, tc.want) {
+				t.Errorf("Got:\n%s\nWant it to start: %s", got, tc.want)
+			}
+			if !tc.prefix && got != tc.want {
+				t.Errorf("Got:\n%s\nWant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReportRequests(t *testing.T) {
+	t.Parallel()
+	// pathOnly answers /aspath without the route and origin.
+	pathOnly := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/aspath/1.1.1.1" {
+			fmt.Fprint(w, `{"Response":{"Action":"aspath","ASPath":["174","13335"],"Exists":true}}`)
+			return
+		}
+		fakeAPI(w, r)
+	}
+	for _, tc := range []struct {
+		handler http.HandlerFunc
+		opts    []Option
+		want    []string
+	}{
+		{handler: fakeAPI, want: []string{"/aspath/1.1.1.1", "/roa/1.1.1.1", "/asname/13335"}},
+		{
+			handler: fakeAPI,
+			opts:    []Option{WithSeparateLookups()},
+			want:    []string{"/route/1.1.1.1", "/origin/1.1.1.1", "/aspath/1.1.1.1", "/roa/1.1.1.1", "/asname/13335"},
+		},
+		{
+			handler: pathOnly,
+			want:    []string{"/aspath/1.1.1.1", "/route/1.1.1.1", "/origin/1.1.1.1", "/aspath/1.1.1.1", "/roa/1.1.1.1", "/asname/13335"},
+		},
+	} {
+		var paths []string
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			tc.handler(w, r)
+		}, tc.opts...)
+		report, err := c.ReportJSON("1.1.1.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(paths, tc.want) {
+			t.Errorf("Got requests: %v, Want: %v", paths, tc.want)
+		}
+		want := `{"ip":"1.1.1.1","route":"1.1.1.0/24","origin":13335,"as_name":"CLOUDFLARENET","as_path":[174,13335],"roa":"VALID"}`
+		if string(report) != want {
+			t.Errorf("Got: %s, Want: %s", report, want)
+		}
+	}
+}
+
+func TestWriteWhois(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+	var b strings.Builder
+	if err := c.WriteWhois(&b, "1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	want := "% bgpstuff.net whois for 1.1.1.1\n\n" +
+		"ip:         1.1.1.1\n" +
+		"route:      1.1.1.0/24\n" +
+		"origin:     AS13335\n" +
+		"as-name:    CLOUDFLARENET\n" +
+		"as-path:    174 13335\n" +
+		"roa-status: VALID\n" +
+		"source:     BGPSTUFF\n"
+	if got := b.String(); got != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", got, want)
+	}
+
+	b.Reset()
+	if err := c.WriteWhois(&b, "19.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "% bgpstuff.net whois for 19.1.1.1\n\n% No route found for 19.1.1.1\n"; b.String() != want {
+		t.Errorf("Got:\n%s\nWant:\n%s", b.String(), want)
+	}
+}
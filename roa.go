@@ -0,0 +1,67 @@
+package bgpstuff
+
+import (
+	"net/netip"
+	"time"
+)
+
+// ROAStatus is the RPKI validation state of a route.
+type ROAStatus string
+
+// ROA statuses returned by the /roa handler.
+const (
+	ROAValid   ROAStatus = "VALID"
+	ROAInvalid ROAStatus = "INVALID"
+	ROAUnknown ROAStatus = "UNKNOWN"
+)
+
+// ROAResult is the RPKI validation state of the route covering an IP,
+// together with the route and origin it was checked against.
+type ROAResult struct {
+	Status ROAStatus
+	Prefix netip.Prefix
+	Origin ASN
+	// CheckedAt is when the server validated the route.
+	CheckedAt time.Time
+}
+
+// GetROADetail uses the /roa handler and returns the status along with the
+// route and origin from the same response, so they can't disagree the way
+// separate GetRoute and GetOrigin calls might. It returns nil if there is
+// no route for ip.
+func (c *Client) GetROADetail(ip string, opts ...CallOption) (*ROAResult, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return nil, lookupError("roa", ip, ErrInvalidIP)
+	}
+
+	call := newCallOptions(opts)
+	resp, err := c.getRequest(call, "roa", p)
+	if err != nil {
+		return nil, lookupError("roa", ip, err)
+	}
+
+	// If there is no origin, there is no prefix ROA to check.
+	if resp.Data.Origin == 0 {
+		return nil, nil
+	}
+
+	result := &ROAResult{
+		Status:    ROAStatus(resp.Data.ROA),
+		Origin:    ASN(resp.Data.Origin),
+		CheckedAt: resp.Data.CacheTime,
+	}
+	if result.CheckedAt.IsZero() {
+		result.CheckedAt = call.result.FetchedAt
+	}
+	if resp.Data.Route != "" {
+		if result.Prefix, err = netip.ParsePrefix(resp.Data.Route); err != nil {
+			return nil, lookupError("roa", ip, err)
+		}
+		if slurm := c.slurmRules(); slurm != nil {
+			result.Status = slurm.Status(result.Status, result.Prefix, result.Origin)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,29 @@
+package bgpstuff
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetROADetail(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, fakeAPI)
+	got, err := c.GetROADetail("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ROAResult{
+		Status: ROAValid,
+		Prefix: netip.MustParsePrefix("1.1.1.0/24"),
+		Origin: 13335,
+	}
+	if got.CheckedAt.IsZero() {
+		t.Error("CheckedAt should be set")
+	}
+	got.CheckedAt = time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %+v, Want: %+v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/export"
+)
+
+// ExportRPSL returns route and route6 object templates for every prefix asn
+// currently originates, to bootstrap its IRR registrations. Use
+// export.RPSL directly to fill in a maintainer and source.
+func (c *Client) ExportRPSL(asn int, opts ...CallOption) (string, error) {
+	prefixes, _, _, err := c.GetSourced(asn, opts...)
+	if err != nil {
+		return "", err
+	}
+	if len(prefixes) == 0 {
+		return "", lookupError("sourced", fmt.Sprint(asn), fmt.Errorf("AS%d originates no prefixes", asn))
+	}
+	return export.RPSL(uint32(asn), prefixes, export.RPSLTemplate{})
+}
+
+// IRRDelta is the difference between the prefixes an AS originates in BGP
+// and those registered for it in an IRR. Each list is sorted.
+type IRRDelta struct {
+	// Unregistered prefixes are announced but have no route object.
+	Unregistered []netip.Prefix
+	// Unannounced prefixes have a route object but are not announced.
+	Unannounced []netip.Prefix
+	// Matched prefixes are both announced and registered.
+	Matched []netip.Prefix
+}
+
+// CompareWithIRR compares the prefixes asn originates, as GetSourced sees
+// them, with irrPrefixes, such as the route objects registered for it.
+// Prefixes must match exactly, as route objects do: a more specific of a
+// registered prefix is unregistered.
+func (c *Client) CompareWithIRR(asn int, irrPrefixes []netip.Prefix, opts ...CallOption) (IRRDelta, error) {
+	nets, _, _, err := c.GetSourced(asn, opts...)
+	if err != nil {
+		return IRRDelta{}, err
+	}
+	announced := make(map[netip.Prefix]bool, len(nets))
+	for _, n := range nets {
+		if p, ok := ipNetPrefix(n); ok {
+			announced[p.Masked()] = true
+		}
+	}
+	registered := make(map[netip.Prefix]bool, len(irrPrefixes))
+	for _, p := range irrPrefixes {
+		registered[p.Masked()] = true
+	}
+
+	var delta IRRDelta
+	for p := range announced {
+		if registered[p] {
+			delta.Matched = append(delta.Matched, p)
+		} else {
+			delta.Unregistered = append(delta.Unregistered, p)
+		}
+	}
+	for p := range registered {
+		if !announced[p] {
+			delta.Unannounced = append(delta.Unannounced, p)
+		}
+	}
+	for _, ps := range [][]netip.Prefix{delta.Unregistered, delta.Unannounced, delta.Matched} {
+		sortPrefixes(ps)
+	}
+	return delta, nil
+}
+
+// sortPrefixes sorts ps by address, IPv4 first, then by length.
+func sortPrefixes(ps []netip.Prefix) {
+	sort.Slice(ps, func(i, j int) bool {
+		if c := ps[i].Addr().Compare(ps[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return ps[i].Bits() < ps[j].Bits()
+	})
+}
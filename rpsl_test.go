@@ -0,0 +1,33 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestCompareWithIRR(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Response":{"Action":"sourced","Sourced":{"Prefixes":["1.1.1.0/24","1.0.0.0/24","1.1.1.0/25","2606:4700::/32"],"Ipv4":3,"Ipv6":1},"Exists":true}}`)
+	})
+	irr := []netip.Prefix{
+		netip.MustParsePrefix("1.1.1.0/24"),
+		netip.MustParsePrefix("2606:4700::/32"),
+		netip.MustParsePrefix("104.16.0.0/13"),
+	}
+	got, err := c.CompareWithIRR(13335, irr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := IRRDelta{
+		Unregistered: []netip.Prefix{netip.MustParsePrefix("1.0.0.0/24"), netip.MustParsePrefix("1.1.1.0/25")},
+		Unannounced:  []netip.Prefix{netip.MustParsePrefix("104.16.0.0/13")},
+		Matched:      []netip.Prefix{netip.MustParsePrefix("1.1.1.0/24"), netip.MustParsePrefix("2606:4700::/32")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %+v, Want: %+v", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+package bgpstuff
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+)
+
+// SLURM holds the local RPKI exceptions from a SLURM file (RFC 8416).
+// BGPSec filters and assertions are parsed but not used.
+type SLURM struct {
+	PrefixFilters    []SLURMPrefixFilter
+	PrefixAssertions []SLURMPrefixAssertion
+}
+
+// SLURMPrefixFilter removes the VRPs it matches. A zero ASN matches any
+// origin, and an invalid Prefix matches any prefix.
+type SLURMPrefixFilter struct {
+	Prefix  netip.Prefix
+	ASN     ASN
+	Comment string
+}
+
+// SLURMPrefixAssertion adds a locally trusted VRP. MaxLength defaults to
+// the length of Prefix.
+type SLURMPrefixAssertion struct {
+	Prefix    netip.Prefix
+	ASN       ASN
+	MaxLength int
+	Comment   string
+}
+
+type slurmFile struct {
+	SLURMVersion int `json:"slurmVersion"`
+	Filters      struct {
+		Prefix []struct {
+			Prefix  string  `json:"prefix"`
+			ASN     *uint32 `json:"asn"`
+			Comment string  `json:"comment"`
+		} `json:"prefixFilters"`
+	} `json:"validationOutputFilters"`
+	Assertions struct {
+		Prefix []struct {
+			Prefix    string `json:"prefix"`
+			ASN       uint32 `json:"asn"`
+			MaxLength int    `json:"maxPrefixLength"`
+			Comment   string `json:"comment"`
+		} `json:"prefixAssertions"`
+	} `json:"locallyAddedAssertions"`
+}
+
+// LoadSLURM reads and checks the SLURM file at path.
+func LoadSLURM(path string) (*SLURM, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f slurmFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("slurm %s: %w", path, err)
+	}
+	if f.SLURMVersion != 1 {
+		return nil, fmt.Errorf("slurm %s: unsupported version %d", path, f.SLURMVersion)
+	}
+
+	s := &SLURM{}
+	for _, pf := range f.Filters.Prefix {
+		if pf.Prefix == "" && pf.ASN == nil {
+			return nil, fmt.Errorf("slurm %s: prefix filter needs a prefix or an asn", path)
+		}
+		filter := SLURMPrefixFilter{Comment: pf.Comment}
+		if pf.Prefix != "" {
+			if filter.Prefix, err = netip.ParsePrefix(pf.Prefix); err != nil {
+				return nil, fmt.Errorf("slurm %s: %w", path, err)
+			}
+		}
+		if pf.ASN != nil {
+			filter.ASN = ASN(*pf.ASN)
+		}
+		s.PrefixFilters = append(s.PrefixFilters, filter)
+	}
+	for _, pa := range f.Assertions.Prefix {
+		assertion := SLURMPrefixAssertion{ASN: ASN(pa.ASN), MaxLength: pa.MaxLength, Comment: pa.Comment}
+		if assertion.Prefix, err = netip.ParsePrefix(pa.Prefix); err != nil {
+			return nil, fmt.Errorf("slurm %s: %w", path, err)
+		}
+		if assertion.MaxLength == 0 {
+			assertion.MaxLength = assertion.Prefix.Bits()
+		}
+		if assertion.MaxLength < assertion.Prefix.Bits() || assertion.MaxLength > assertion.Prefix.Addr().BitLen() {
+			return nil, fmt.Errorf("slurm %s: bad maxPrefixLength %d for %s", path, assertion.MaxLength, assertion.Prefix)
+		}
+		s.PrefixAssertions = append(s.PrefixAssertions, assertion)
+	}
+
+	return s, nil
+}
+
+// ApplySLURM loads the SLURM file at path and applies its exceptions to
+// every later ROA answer and invalids table, and to the VRPs CheckROA
+// validates against, so the client reports the state a router using the
+// same file would compute.
+func (c *Client) ApplySLURM(path string) error {
+	s, err := LoadSLURM(path)
+	if err != nil {
+		return err
+	}
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	c.slurm = s
+	c.indexVRPs()
+	return nil
+}
+
+// slurmRules returns the SLURM file applied with ApplySLURM, if any.
+func (c *Client) slurmRules() *SLURM {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	return c.slurm
+}
+
+// filtered reports whether a filter removes v.
+func (s *SLURM) filtered(v VRP) bool {
+	for _, f := range s.PrefixFilters {
+		if f.ASN != 0 && f.ASN != v.ASN {
+			continue
+		}
+		if f.Prefix.IsValid() && !covers(f.Prefix, v.Prefix) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// apply returns vrps with those matched by a filter removed and the
+// assertions added (RFC 8416, section 4.2).
+func (s *SLURM) apply(vrps []VRP) []VRP {
+	out := make([]VRP, 0, len(vrps)+len(s.PrefixAssertions))
+	for _, v := range vrps {
+		if !s.filtered(v) {
+			out = append(out, v)
+		}
+	}
+	for _, a := range s.PrefixAssertions {
+		out = append(out, VRP{Prefix: a.Prefix.Masked(), MaxLength: a.MaxLength, ASN: a.ASN})
+	}
+	return out
+}
+
+// Status returns the validation state of prefix originated by origin,
+// given the server's state for it.
+//
+// The server does not publish its VRPs, so for its answers the effective
+// VRP set can't be built as RFC 8416 asks; instead filters are applied to
+// the route itself: a route inside a filter's prefix, and from the filter's
+// ASN if it has one, loses the server's state and becomes UNKNOWN. An
+// assertion covering the route then makes it VALID if the origin and
+// length match, or INVALID otherwise.
+func (s *SLURM) Status(status ROAStatus, prefix netip.Prefix, origin ASN) ROAStatus {
+	for _, f := range s.PrefixFilters {
+		if f.ASN != 0 && f.ASN != origin {
+			continue
+		}
+		if f.Prefix.IsValid() && !covers(f.Prefix, prefix) {
+			continue
+		}
+		status = ROAUnknown
+		break
+	}
+
+	covered := false
+	for _, a := range s.PrefixAssertions {
+		if !covers(a.Prefix, prefix) {
+			continue
+		}
+		if a.ASN == origin && prefix.Bits() <= a.MaxLength {
+			return ROAValid
+		}
+		covered = true
+	}
+	if covered && status == ROAUnknown {
+		return ROAInvalid
+	}
+
+	return status
+}
+
+// filterInvalids drops the routes that are no longer invalid once the
+// exceptions are applied. Routes can't be added, as the server only lists
+// the ones it considers invalid.
+func (s *SLURM) filterInvalids(invalids map[int][]*net.IPNet) {
+	for asn, nets := range invalids {
+		kept := nets[:0]
+		for _, n := range nets {
+			p, ok := ipNetPrefix(n)
+			if !ok || s.Status(ROAInvalid, p, ASN(asn)) == ROAInvalid {
+				kept = append(kept, n)
+			}
+		}
+		if len(kept) == 0 {
+			delete(invalids, asn)
+			continue
+		}
+		invalids[asn] = kept
+	}
+}
+
+// covers reports whether outer contains all of inner.
+func covers(outer, inner netip.Prefix) bool {
+	return outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr())
+}
+
+func ipNetPrefix(n *net.IPNet) (netip.Prefix, bool) {
+	addr, ok := netip.AddrFromSlice(n.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	bits, _ := n.Mask.Size()
+	return netip.PrefixFrom(addr.Unmap(), bits), true
+}
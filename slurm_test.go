@@ -0,0 +1,66 @@
+package bgpstuff
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplySLURM(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "slurm.json")
+	slurm := `{
+  "slurmVersion": 1,
+  "validationOutputFilters": {
+    "prefixFilters": [{"prefix": "1.1.1.0/24", "comment": "ignore the published ROA"}],
+    "bgpsecFilters": []
+  },
+  "locallyAddedAssertions": {
+    "prefixAssertions": [
+      {"asn": 64496, "prefix": "1.1.0.0/16", "maxPrefixLength": 24},
+      {"asn": 64497, "prefix": "192.0.2.0/24"}
+    ],
+    "bgpsecAssertions": []
+  }
+}`
+	if err := os.WriteFile(path, []byte(slurm), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, fakeAPI)
+	if err := c.ApplySLURM(path); err != nil {
+		t.Fatal(err)
+	}
+
+	roa, err := c.GetROA("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roa != "INVALID" {
+		t.Errorf("Got: %s, Want: INVALID", roa)
+	}
+	detail, err := c.GetROADetail("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Status != ROAInvalid {
+		t.Errorf("Got: %s, Want: INVALID", detail.Status)
+	}
+
+	_, n1, _ := net.ParseCIDR("192.0.2.0/24")
+	_, n2, _ := net.ParseCIDR("198.51.100.0/24")
+	invalids := map[int][]*net.IPNet{64497: {n1}, 64498: {n2}}
+	c.slurm.filterInvalids(invalids)
+	want := map[int][]*net.IPNet{64498: {n2}}
+	if !reflect.DeepEqual(invalids, want) {
+		t.Errorf("Got: %v, Want: %v", invalids, want)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"slurmVersion": 2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplySLURM(path); err == nil {
+		t.Error("Expected an error for an unsupported version")
+	}
+}
@@ -0,0 +1,98 @@
+package bgpstuff
+
+import (
+	"math"
+	"net"
+	"net/netip"
+)
+
+// PrefixStats summarises a set of prefixes, such as those sourced by an AS.
+type PrefixStats struct {
+	IPv4, IPv6 int
+	// IPv4Addresses is the IPv4 address space covered, in /32s.
+	IPv4Addresses uint64
+	// IPv6Slash48s is the IPv6 address space covered, in /48s. Prefixes
+	// longer than /48 count as a fraction of one.
+	IPv6Slash48s float64
+	// The shortest and longest prefix lengths seen, or 0 if there are no
+	// prefixes of that family.
+	MinIPv4Len, MaxIPv4Len int
+	MinIPv6Len, MaxIPv6Len int
+}
+
+// IPv6Share returns the fraction of prefixes that are IPv6, or 0 if there
+// are none.
+func (s PrefixStats) IPv6Share() float64 {
+	if s.IPv4+s.IPv6 == 0 {
+		return 0
+	}
+	return float64(s.IPv6) / float64(s.IPv4+s.IPv6)
+}
+
+// PrefixStatsOf returns statistics for prefixes. Address space that more than one
+// prefix covers, such as a more specific of another prefix in the list, is
+// only counted once. Duplicate prefixes are counted once.
+func PrefixStatsOf(prefixes []*net.IPNet) PrefixStats {
+	seen := make(map[netip.Prefix]bool, len(prefixes))
+	ps := make([]netip.Prefix, 0, len(prefixes))
+	for _, n := range prefixes {
+		p, ok := ipNetPrefix(n)
+		if !ok || seen[p.Masked()] {
+			continue
+		}
+		seen[p.Masked()] = true
+		ps = append(ps, p.Masked())
+	}
+	// Shortest first at each address, so any prefix covering another comes
+	// before it.
+	sortPrefixes(ps)
+
+	var s PrefixStats
+	var last netip.Prefix
+	for _, p := range ps {
+		bits := p.Bits()
+		if p.Addr().Is4() {
+			s.IPv4++
+			s.MinIPv4Len, s.MaxIPv4Len = minLen(s.MinIPv4Len, bits, s.IPv4), maxInt(s.MaxIPv4Len, bits)
+		} else {
+			s.IPv6++
+			s.MinIPv6Len, s.MaxIPv6Len = minLen(s.MinIPv6Len, bits, s.IPv6), maxInt(s.MaxIPv6Len, bits)
+		}
+		if last.IsValid() && last.Overlaps(p) {
+			continue
+		}
+		last = p
+		if p.Addr().Is4() {
+			s.IPv4Addresses += 1 << (32 - bits)
+		} else {
+			s.IPv6Slash48s += math.Pow(2, float64(48-bits))
+		}
+	}
+	return s
+}
+
+// minLen returns the smaller of cur and bits, treating cur as unset if
+// bits is from the first prefix seen.
+func minLen(cur, bits, count int) int {
+	if count == 1 || bits < cur {
+		return bits
+	}
+	return cur
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// SourcedStats looks up the prefixes sourced by asn and returns their
+// statistics.
+func (c *Client) SourcedStats(asn int, opts ...CallOption) (PrefixStats, error) {
+	prefixes, _, _, err := c.GetSourced(asn, opts...)
+	if err != nil {
+		return PrefixStats{}, err
+	}
+	return PrefixStatsOf(prefixes), nil
+}
@@ -0,0 +1,439 @@
+package bgpstuff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/api"
+)
+
+// Files kept in a store directory.
+const (
+	storeASNames  = "asnames.json"
+	storeInvalids = "invalids.json"
+	storeHistory  = "history.jsonl"
+)
+
+// defaultStoreHistoryBytes is how large the history may grow before the
+// oldest answers are dropped, unless set with WithStoreHistoryLimit.
+const defaultStoreHistoryBytes = 64 << 20
+
+// WithStore keeps what the client fetches in the directory at path, which
+// is created if needed, so it outlives the program:
+//
+//   - the tables loaded by GetASNames and GetInvalids, which are loaded
+//     back in when the next client using the store is created;
+//   - every answer fetched for a lookup, including GetSourced, which
+//     History and OriginHistory read back.
+//
+// If the client is also created WithStaleIfError, lookups are answered from
+// the last answer in the store when the API can't be reached, as long as it
+// is no older than the stale limit. The store is plain files, so it may be
+// shared by clients in turn but not at once. Problems reading or writing it
+// don't fail lookups; StoreErr reports the last one.
+//
+// The history is capped in size, see WithStoreHistoryLimit. Only the last
+// answers to as many queries as the cache holds, see WithCacheSize, are
+// kept in memory to answer from.
+func WithStore(path string) Option {
+	return func(c *Client) {
+		c.storeDir = path
+	}
+}
+
+// openStore opens the store set with WithStore, once all the options have
+// been applied, and loads the tables kept in it. With WithInvalidCountsOnly
+// only the counts of the invalids table are loaded.
+func (c *Client) openStore() {
+	maxHistory := int64(defaultStoreHistoryBytes)
+	if c.storeHistoryLimit != 0 {
+		maxHistory = c.storeHistoryLimit
+	}
+	c.store = openStore(c.storeDir, maxHistory, c.cacheSize)
+
+	var names storedASNames
+	if ok := c.store.readJSON(storeASNames, &names); ok {
+		asinfo := make(map[uint32]ASNumName, len(names.ASNames))
+		byASN := make(map[int]string, len(names.ASNames))
+		for _, v := range names.ASNames {
+			asinfo[v.ASN] = v
+			byASN[int(v.ASN)] = v.ASName
+		}
+		c.setASTable(asinfo, byASN, names.Time)
+	}
+
+	var stored storedInvalids
+	if ok := c.store.readJSON(storeInvalids, &stored); !ok {
+		return
+	}
+	if c.invalidCountsOnly {
+		counts := make(map[int]int, len(stored.Invalids))
+		for asn, prefixes := range stored.Invalids {
+			counts[asn] = len(prefixes)
+		}
+		c.setInvalidCounts(counts)
+		return
+	}
+	invalids := make(map[int][]*net.IPNet, len(stored.Invalids))
+	for asn, prefixes := range stored.Invalids {
+		nets, err := parseCIDRs(prefixes)
+		if err != nil {
+			c.store.setErr(fmt.Errorf("store %s: %w", storeInvalids, err))
+			return
+		}
+		invalids[asn] = nets
+	}
+	c.setInvalidTable(invalids)
+}
+
+// WithStoreHistoryLimit caps the history kept by WithStore at about n bytes.
+// When it grows past n the oldest answers are dropped, leaving about half
+// of n, so History and OriginHistory never have an unbounded file to read.
+// The default is 64 MiB.
+func WithStoreHistoryLimit(n int64) Option {
+	return func(c *Client) {
+		c.storeHistoryLimit = n
+	}
+}
+
+// Observation is an answer the client fetched from the API, as kept by
+// WithStore.
+type Observation struct {
+	Time time.Time
+	// Endpoint is the endpoint asked, such as "origin", and Query what was
+	// asked of it, such as "1.1.1.1". Query is empty for endpoints that
+	// take no argument.
+	Endpoint string
+	Query    string
+	Data     api.Data
+}
+
+// OriginChange is a point at which the origin seen for a prefix changed.
+type OriginChange struct {
+	// Time is when the new origin was first seen.
+	Time time.Time
+	// Origin is the new origin, or 0 if no route was seen.
+	Origin int
+}
+
+// store is the on-disk state kept by WithStore.
+type store struct {
+	dir string
+
+	mu  sync.Mutex
+	err error
+	// historySize is the size of the history file, and maxHistory the
+	// size at which it is trimmed.
+	historySize int64
+	maxHistory  int64
+	// latest holds the last observation for the most recently used
+	// queries, by URL path.
+	latest *responseCache
+}
+
+type storedInvalids struct {
+	Time     time.Time
+	Invalids map[int][]string
+}
+
+type storedASNames struct {
+	Time    time.Time
+	ASNames []ASNumName
+}
+
+// openStore opens the store in dir and reads the last answers to up to
+// latest queries from its history, which is trimmed at maxHistory bytes.
+func openStore(dir string, maxHistory int64, latest int) *store {
+	s := &store{dir: dir, latest: newResponseCache(latest), maxHistory: maxHistory}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		s.err = err
+		return s
+	}
+
+	err := s.scan(s.remember)
+	if err != nil {
+		s.err = err
+	}
+	if fi, err := os.Stat(filepath.Join(s.dir, storeHistory)); err == nil {
+		s.historySize = fi.Size()
+	}
+	return s
+}
+
+// readJSON decodes the file name in the store into v, reporting whether
+// there was one to decode.
+func (s *store) readJSON(name string, v interface{}) bool {
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err == nil {
+		err = json.Unmarshal(b, v)
+	}
+	if err != nil {
+		s.err = fmt.Errorf("store %s: %w", name, err)
+		return false
+	}
+	return true
+}
+
+// writeJSON replaces the file name in the store with v.
+func (s *store) writeJSON(name string, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.setErr(err)
+		return
+	}
+	s.setErr(s.replace(name, b))
+}
+
+// replace replaces the file name in the store with b, by way of a temporary
+// file of its own so neither a crash nor another writer can leave it half
+// written.
+func (s *store) replace(name string, b []byte) error {
+	tmp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, name))
+}
+
+func (s *store) setErr(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// historyKey is the URL path of a query, such as "origin/1.1.1.1".
+func historyKey(endpoint, query string) string {
+	if query == "" {
+		return endpoint
+	}
+	return endpoint + "/" + query
+}
+
+// record adds an answer fetched for the query made up of urls to the
+// history. The tables are kept in their own files instead.
+func (s *store) record(urls []string, d *data, fetched time.Time) {
+	if urls[0] == "asnames" {
+		return
+	}
+	o := Observation{
+		Time:     fetched,
+		Endpoint: urls[0],
+		Query:    strings.Join(urls[1:], "/"),
+		Data:     *d,
+	}
+	line, err := json.Marshal(o)
+	if err != nil {
+		s.setErr(err)
+		return
+	}
+
+	s.remember(o)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(s.dir, storeHistory), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		s.err = err
+	}
+	if err := f.Close(); err != nil {
+		s.err = err
+	}
+	s.historySize += int64(len(line)) + 1
+	if s.maxHistory > 0 && s.historySize > s.maxHistory {
+		if err := s.trimHistory(); err != nil {
+			s.err = err
+		}
+	}
+}
+
+// trimHistory drops the oldest answers from the history, keeping the newest
+// that fit in half of maxHistory. s.mu must be held.
+func (s *store) trimHistory() error {
+	b, err := os.ReadFile(filepath.Join(s.dir, storeHistory))
+	if err != nil {
+		return err
+	}
+	keep := s.maxHistory / 2
+	start := 0
+	if int64(len(b)) > keep {
+		start = len(b) - int(keep)
+		// Start at the first whole line.
+		if i := bytes.IndexByte(b[start-1:], '\n'); i >= 0 {
+			start += i
+		} else {
+			start = len(b)
+		}
+	}
+	if err := s.replace(storeHistory, b[start:]); err != nil {
+		return err
+	}
+	s.historySize = int64(len(b) - start)
+	return nil
+}
+
+// remember makes o the last answer to its query.
+func (s *store) remember(o Observation) {
+	s.latest.put(historyKey(o.Endpoint, o.Query), cacheEntry{
+		endpoint: o.Endpoint,
+		resp:     &response{Data: o.Data},
+		fetched:  o.Time,
+	})
+}
+
+// last returns the last answer stored for the query made up of urls, if
+// it is still held.
+func (s *store) last(urls []string) (Observation, bool) {
+	s.latest.mu.Lock()
+	entry, ok := s.latest.lookup(strings.Join(urls, "/"))
+	s.latest.mu.Unlock()
+	if !ok {
+		return Observation{}, false
+	}
+	return Observation{
+		Time:     entry.fetched,
+		Endpoint: urls[0],
+		Query:    strings.Join(urls[1:], "/"),
+		Data:     entry.resp.Data,
+	}, true
+}
+
+// scan calls fn with each observation in the history, oldest first.
+func (s *store) scan(fn func(Observation)) error {
+	f, err := os.Open(filepath.Join(s.dir, storeHistory))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var o Observation
+		if err := json.Unmarshal(line, &o); err != nil {
+			return fmt.Errorf("store %s: %w", storeHistory, err)
+		}
+		fn(o)
+	}
+	return scanner.Err()
+}
+
+// saveASNames keeps a table loaded by GetASNames.
+func (s *store) saveASNames(names []ASNumName, fetched time.Time) {
+	s.writeJSON(storeASNames, storedASNames{Time: fetched, ASNames: names})
+}
+
+// saveInvalids keeps a table loaded by GetInvalids.
+func (s *store) saveInvalids(invalids map[int][]*net.IPNet) {
+	stored := storedInvalids{Time: time.Now(), Invalids: make(map[int][]string, len(invalids))}
+	for asn, prefixes := range invalids {
+		strs := make([]string, len(prefixes))
+		for i, p := range prefixes {
+			strs[i] = p.String()
+		}
+		stored.Invalids[asn] = strs
+	}
+	s.writeJSON(storeInvalids, stored)
+}
+
+// StoreErr returns the last error reading or writing the store kept by
+// WithStore, or nil if there has been none or there is no store.
+func (c *Client) StoreErr() error {
+	if c.store == nil {
+		return nil
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	return c.store.err
+}
+
+// History returns the answers kept by WithStore for endpoint, oldest
+// first. If query is not empty only answers to it are returned; IPs are
+// matched in their normalized form.
+func (c *Client) History(endpoint, query string) ([]Observation, error) {
+	if c.store == nil {
+		return nil, errors.New("no store, see WithStore")
+	}
+	if ip, ok := normalizeIP(query); ok {
+		query = ip
+	}
+	var obs []Observation
+	err := c.store.scan(func(o Observation) {
+		if o.Endpoint == endpoint && (query == "" || o.Query == query) {
+			obs = append(obs, o)
+		}
+	})
+	return obs, err
+}
+
+// OriginHistory returns each time the origin of ip, or of a prefix's
+// network address, was seen to change in the answers kept by WithStore.
+// Answers from both /origin and /roa count. The first entry is the first
+// origin seen.
+func (c *Client) OriginHistory(ip string) ([]OriginChange, error) {
+	p, ok := c.validIP(ip)
+	if !ok {
+		return nil, lookupError("origin", ip, ErrInvalidIP)
+	}
+	var changes []OriginChange
+	var obs []Observation
+	for _, endpoint := range []string{"origin", "roa"} {
+		o, err := c.History(endpoint, p)
+		if err != nil {
+			return nil, err
+		}
+		obs = append(obs, o...)
+	}
+	sortObservations(obs)
+	for _, o := range obs {
+		if n := len(changes); n > 0 && changes[n-1].Origin == o.Data.Origin {
+			continue
+		}
+		changes = append(changes, OriginChange{Time: o.Time, Origin: o.Data.Origin})
+	}
+	return changes, nil
+}
+
+// sortObservations sorts obs oldest first, keeping the order of those made
+// at the same time.
+func sortObservations(obs []Observation) {
+	sort.SliceStable(obs, func(i, j int) bool { return obs[i].Time.Before(obs[j].Time) })
+}
@@ -0,0 +1,169 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var down int32
+	handler := downAPI(&down, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/asnames":
+			fmt.Fprint(w, `{"Response":{"Action":"asnames","ASNames":[{"ASN":13335,"ASName":"CLOUDFLARENET","Locale":"US"},{"ASN":15169,"ASName":"GOOGLE","Locale":"US"}],"Exists":true}}`)
+		default:
+			fakeAPI(w, r)
+		}
+	})
+
+	c := newTestClient(t, handler, WithStore(dir))
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetROA("1.1.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetASNames(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.StoreErr(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new client using the store answers from it while the API is down,
+	// but only if it allows stale answers.
+	atomic.StoreInt32(&down, apiDown)
+	c = newTestClient(t, handler, WithStore(dir))
+	if _, err := c.GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected error without WithStaleIfError, but no error returned")
+	}
+	if _, err := newTestClient(t, handler, WithStore(dir), WithStaleIfError(time.Nanosecond)).GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected error for an answer older than the stale limit, but no error returned")
+	}
+	c = newTestClient(t, handler, WithStore(dir), WithStaleIfError(time.Hour))
+	var meta Meta
+	origin, err := c.GetOrigin("1.1.1.1", WithMeta(&meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != 13335 || !meta.Stale {
+		t.Errorf("Got: %d stale=%t, Want: 13335 stale=true", origin, meta.Stale)
+	}
+	if name, err := c.GetASName(15169); err != nil || name != "GOOGLE" {
+		t.Errorf("GetASName(15169) = %q, %v, Want: GOOGLE", name, err)
+	}
+	if _, err := c.GetOrigin("1.1.1.1", NoCache()); err == nil {
+		t.Error("Expected error with NoCache, but no error returned")
+	}
+	// An API that answers with an error is not reported as down.
+	atomic.StoreInt32(&down, apiGone)
+	if _, err := c.GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected error for a 404, but no error returned")
+	}
+
+	obs, err := c.History("origin", "1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) != 1 || obs[0].Data.Origin != 13335 {
+		t.Errorf("Got history: %+v, Want one answer of 13335", obs)
+	}
+	changes, err := c.OriginHistory("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Origin != 13335 {
+		t.Errorf("Got origin changes: %+v, Want one change to 13335", changes)
+	}
+}
+
+func TestStoreHistoryLimit(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	c := newTestClient(t, fakeAPI, WithLimiter(&countingLimiter{}), WithStore(dir), WithStoreHistoryLimit(1024))
+	for i := 0; i < 50; i++ {
+		if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.GetRoute("1.1.1.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.StoreErr(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dir, storeHistory))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 1024 {
+		t.Errorf("Got history of %d bytes, Want at most 1024", fi.Size())
+	}
+	obs, err := c.History("route", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) == 0 || len(obs) >= 50 {
+		t.Errorf("Got %d route answers, Want some but not all 50", len(obs))
+	}
+
+	// Tables written at once each land whole.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.store.saveASNames([]ASNumName{{ASN: 13335, ASName: "CLOUDFLARENET"}}, time.Now())
+		}()
+	}
+	wg.Wait()
+	if err := c.StoreErr(); err != nil {
+		t.Fatal(err)
+	}
+	reopened := NewBGPClient(true, WithStore(dir))
+	if names := reopened.ASNameTable(); reopened.StoreErr() != nil || len(names) != 1 {
+		t.Errorf("Got %d names, error %v, Want 1 name", len(names), reopened.StoreErr())
+	}
+
+	// Only as many last answers as the cache holds are kept in memory.
+	small := NewBGPClient(true, WithStore(dir), WithCacheSize(1))
+	if n := small.store.latest.len(); n != 1 {
+		t.Errorf("Got %d last answers in memory, Want 1", n)
+	}
+}
+
+func TestStoreLoadsTables(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Response":{"Action":"invalids","Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25","1.1.1.128/25"]}]}}`)
+	}, WithStore(dir))
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The counts-only mode is honoured whichever order the options come in.
+	for _, opts := range [][]Option{
+		{WithStore(dir), WithInvalidCountsOnly()},
+		{WithInvalidCountsOnly(), WithStore(dir)},
+	} {
+		c := NewBGPClient(true, opts...)
+		if c.InvalidTable() != nil {
+			t.Error("Got the full invalids table, Want only counts")
+		}
+		if got, err := c.InvalidCount(13335); err != nil || got != 2 {
+			t.Errorf("InvalidCount(13335) = %d, %v, Want: 2", got, err)
+		}
+	}
+	if got := NewBGPClient(true, WithStore(dir)).InvalidTable(); len(got[13335]) != 2 {
+		t.Errorf("Got: %v, Want AS13335's two prefixes", got)
+	}
+}
@@ -0,0 +1,229 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// Topic is a dataset that can be watched with Subscribe.
+type Topic int
+
+// Topics that can be passed to Subscribe.
+const (
+	TopicTotals Topic = iota
+	TopicInvalids
+)
+
+func (t Topic) String() string {
+	switch t {
+	case TopicTotals:
+		return "totals"
+	case TopicInvalids:
+		return "invalids"
+	}
+	return "unknown"
+}
+
+const defaultPollInterval = time.Minute
+
+// Event is sent on a subscription channel whenever a watched dataset changes.
+type Event struct {
+	Topic Topic
+	Time  time.Time
+
+	// Totals is set for TopicTotals events.
+	Totals Totals
+
+	// Added and Removed are set for TopicInvalids events, keyed by origin
+	// ASN. The first event on a subscription lists every invalid as Added.
+	Added   map[int][]*net.IPNet
+	Removed map[int][]*net.IPNet
+
+	// Err is set if the dataset could not be fetched. The subscription
+	// keeps running and tries again at the next interval.
+	Err error
+}
+
+// Notifier is told of the changes seen by Subscribe. See WithNotifier.
+type Notifier interface {
+	// Notify is called with each event after the first for its topic,
+	// which only describes the starting state. Events with Err set are
+	// not passed on. It is called from the goroutine polling the API, so
+	// a slow notifier delays the next poll. Errors are passed to the
+	// handler set with WithNotifyErrorHandler.
+	Notify(ctx context.Context, ev Event) error
+}
+
+// Subscribe watches the given topics and sends an Event each time one of
+// them changes. The current state of each topic is sent straight away.
+// bgpstuff.net has no push channel, so this is not a live subscription:
+// topics are polled at the interval set by WithPollInterval, changes are
+// seen up to an interval late, and there is no backfill of changes made
+// before Subscribe was called. Each interval costs one /totals request
+// whichever topics are watched, and the invalids table is only downloaded
+// when that shows the RIB has changed. The channel is closed once ctx is
+// done.
+func (c *Client) Subscribe(ctx context.Context, topics ...Topic) (<-chan Event, error) {
+	if len(topics) == 0 {
+		return nil, errors.New("no topics to subscribe to")
+	}
+	for _, t := range topics {
+		if t != TopicTotals && t != TopicInvalids {
+			return nil, errors.New("unknown topic")
+		}
+	}
+
+	interval := c.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ctx, end, err := c.life.begin(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer end()
+		defer close(events)
+		w := &watcher{}
+		started := make(map[Topic]bool)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			w.tick()
+			for _, t := range topics {
+				if ev, changed := w.poll(ctx, c, t); changed {
+					if ev.Err == nil {
+						if started[t] {
+							c.notify(ctx, ev)
+						}
+						started[t] = true
+					}
+					select {
+					case events <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// notify passes ev to each notifier. A notifier failing doesn't stop the
+// others or the subscription, and is reported to the handler set with
+// WithNotifyErrorHandler.
+func (c *Client) notify(ctx context.Context, ev Event) {
+	for _, n := range c.notifiers {
+		if err := n.Notify(ctx, ev); err != nil && c.notifyErr != nil {
+			c.notifyErr(n, ev, err)
+		}
+	}
+}
+
+// watcher remembers the last state seen for each topic.
+type watcher struct {
+	totals   *Totals
+	invalids map[int][]*net.IPNet
+	// invalidsAt is the RIB the invalids were last pulled from.
+	invalidsAt *Totals
+
+	// fetched, rib and ribErr hold this tick's /totals answer, so topics
+	// polled in the same tick share one request.
+	fetched bool
+	rib     Totals
+	ribErr  error
+}
+
+// tick starts a new round of polls.
+func (w *watcher) tick() {
+	w.fetched = false
+}
+
+// currentTotals returns the totals for this tick, asking /totals the first
+// time it is called in the tick.
+func (w *watcher) currentTotals(ctx context.Context, c *Client) (Totals, error) {
+	if !w.fetched {
+		w.fetched = true
+		resp, err := c.getRequest(&callOptions{ctx: ctx, priority: PriorityLow}, "totals")
+		if err != nil {
+			w.rib, w.ribErr = Totals{}, lookupError("totals", "", err)
+		} else {
+			w.rib, w.ribErr = resp.Data.Totals, nil
+		}
+	}
+	return w.rib, w.ribErr
+}
+
+// poll fetches topic and returns an event if it differs from the last poll.
+func (w *watcher) poll(ctx context.Context, c *Client, topic Topic) (Event, bool) {
+	ev := Event{Topic: topic, Time: time.Now()}
+	switch topic {
+	case TopicTotals:
+		totals, err := w.currentTotals(ctx, c)
+		if err != nil {
+			ev.Err = err
+			return ev, ctx.Err() == nil
+		}
+		if w.totals != nil && w.totals.Ipv4 == totals.Ipv4 && w.totals.Ipv6 == totals.Ipv6 {
+			return ev, false
+		}
+		w.totals = &totals
+		ev.Totals = totals
+
+	case TopicInvalids:
+		// The invalids table is large, so it is only pulled again once the
+		// small /totals answer shows the RIB it is built from has changed.
+		// If /totals can't be read the table is pulled anyway.
+		var rib *Totals
+		if totals, err := w.currentTotals(ctx, c); err == nil {
+			rib = &totals
+			if w.invalidsAt != nil && *w.invalidsAt == *rib {
+				return ev, false
+			}
+		}
+		invalids, err := c.fetchInvalids(&callOptions{ctx: ctx, priority: PriorityLow}, nil)
+		if err != nil {
+			ev.Err = err
+			return ev, ctx.Err() == nil
+		}
+		w.invalidsAt = rib
+		first := w.invalids == nil
+		ev.Added = diffInvalids(invalids, w.invalids)
+		ev.Removed = diffInvalids(w.invalids, invalids)
+		w.invalids = invalids
+		if !first && len(ev.Added) == 0 && len(ev.Removed) == 0 {
+			return ev, false
+		}
+	}
+	return ev, true
+}
+
+// diffInvalids returns copies of the prefixes in a that are not in b, so
+// subscribers can't change the state the next poll is compared with.
+func diffInvalids(a, b map[int][]*net.IPNet) map[int][]*net.IPNet {
+	diff := make(map[int][]*net.IPNet)
+	for asn, prefixes := range a {
+		seen := make(map[string]bool, len(b[asn]))
+		for _, p := range b[asn] {
+			seen[p.String()] = true
+		}
+		for _, p := range prefixes {
+			if !seen[p.String()] {
+				diff[asn] = append(diff[asn], cloneIPNets([]*net.IPNet{p})...)
+			}
+		}
+	}
+	return diff
+}
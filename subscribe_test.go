@@ -0,0 +1,141 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type notifierFunc func(context.Context, Event) error
+
+func (f notifierFunc) Notify(ctx context.Context, ev Event) error { return f(ctx, ev) }
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+	// rib is the RIB snapshot the server is on. The invalids only change
+	// with it, so the table is pulled once per snapshot.
+	var rib, pulls int32 = 1, 0
+	var notified []Event
+	notifier := notifierFunc(func(_ context.Context, ev Event) error {
+		notified = append(notified, ev)
+		return nil
+	})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		snapshot := atomic.LoadInt32(&rib)
+		switch r.URL.Path {
+		case "/totals":
+			fmt.Fprintf(w, `{"Response":{"Action":"totals","Totals":{"Ipv4":900000,"Ipv6":150000,"Time":%d}}}`, snapshot)
+		case "/invalids":
+			atomic.AddInt32(&pulls, 1)
+			if snapshot == 1 {
+				fmt.Fprint(w, `{"Response":{"Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25"]}]}}`)
+				return
+			}
+			fmt.Fprint(w, `{"Response":{"Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25","1.1.1.128/25"]}]}}`)
+		}
+	}, WithPollInterval(time.Millisecond), WithNotifier(notifier), WithLimiter(&countingLimiter{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := c.Subscribe(ctx, TopicInvalids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-events
+	if first.Err != nil || len(first.Added[13335]) != 1 {
+		t.Errorf("Got first event: %+v, Want one added prefix", first)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt32(&pulls); got != 1 {
+		t.Errorf("Got %d pulls of /invalids on one RIB snapshot, Want: 1", got)
+	}
+
+	atomic.StoreInt32(&rib, 2)
+	second := <-events
+	if second.Err != nil || len(second.Added[13335]) != 1 || second.Added[13335][0].String() != "1.1.1.128/25" {
+		t.Errorf("Got second event: %+v, Want 1.1.1.128/25 added", second)
+	}
+	// The notifier is told of the change, but not of the starting state.
+	if len(notified) != 1 || len(notified[0].Added[13335]) != 1 {
+		t.Errorf("Got notified: %+v, Want only the second event", notified)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestNotifyErrors(t *testing.T) {
+	t.Parallel()
+	var polls int32
+	failing := notifierFunc(func(context.Context, Event) error { return errors.New("403 Forbidden") })
+	var notified int32
+	working := notifierFunc(func(context.Context, Event) error {
+		atomic.AddInt32(&notified, 1)
+		return nil
+	})
+	errs := make(chan error, 1)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Response":{"Action":"totals","Totals":{"Ipv4":%d,"Ipv6":1}}}`, atomic.AddInt32(&polls, 1))
+	}, WithPollInterval(time.Millisecond), WithLimiter(&countingLimiter{}),
+		WithNotifier(failing), WithNotifier(working),
+		WithNotifyErrorHandler(func(n Notifier, ev Event, err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := c.Subscribe(ctx, TopicTotals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+	<-events
+	if err := <-errs; err == nil || err.Error() != "403 Forbidden" {
+		t.Errorf("Got: %v, Want the notifier's error", err)
+	}
+	// The failing notifier doesn't stop the others.
+	if atomic.LoadInt32(&notified) == 0 {
+		t.Error("Working notifier was not called")
+	}
+	cancel()
+	for range events {
+	}
+}
+
+func TestSubscribeSharesTotals(t *testing.T) {
+	t.Parallel()
+	var totals, invalids int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/totals":
+			atomic.AddInt32(&totals, 1)
+			fmt.Fprint(w, `{"Response":{"Action":"totals","Totals":{"Ipv4":900000,"Ipv6":150000,"Time":1}}}`)
+		case "/invalids":
+			atomic.AddInt32(&invalids, 1)
+			fmt.Fprint(w, `{"Response":{"Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25"]}]}}`)
+		}
+	}, WithPollInterval(time.Hour), WithLimiter(&countingLimiter{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := c.Subscribe(ctx, TopicTotals, TopicInvalids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+	<-events
+	if got, want := [2]int32{atomic.LoadInt32(&totals), atomic.LoadInt32(&invalids)}, [2]int32{1, 1}; got != want {
+		t.Errorf("Got %d /totals and %d /invalids requests in one tick, Want one of each", got[0], got[1])
+	}
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,249 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// ErrCacheCold is returned by methods that answer from a table the client
+// loads in bulk, when the table has not been loaded yet.
+type ErrCacheCold struct {
+	// Cache is the table that is not loaded: "asnames" or "invalids".
+	Cache string
+}
+
+func (e ErrCacheCold) Error() string {
+	loader := map[string]string{"asnames": "GetASNames", "invalids": "GetInvalids"}[e.Cache]
+	if loader == "" {
+		return fmt.Sprintf("%s is empty", e.Cache)
+	}
+	return fmt.Sprintf("%s is empty, run %s() or Ensure%s first", e.Cache, loader, loader[3:])
+}
+
+// asTable returns the AS table loaded by GetASNames, which must not be
+// modified.
+func (c *Client) asTable() map[uint32]ASNumName {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	return c.asinfo
+}
+
+// invalidTable returns the invalids table loaded by GetInvalids, which
+// must not be modified.
+func (c *Client) invalidTable() map[int][]*net.IPNet {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	return c.invalids
+}
+
+// setASTable swaps in a complete new AS table, so readers see either the
+// old table or the new one and never one part filled.
+// fetched is when the table was fetched from the API.
+func (c *Client) setASTable(asinfo map[uint32]ASNumName, names map[int]string, fetched time.Time) {
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	c.asinfo = asinfo
+	c.asinfoFetched = fetched
+	c.ASNames = names
+}
+
+// asTableFetched returns when the AS table was fetched from the API.
+func (c *Client) asTableFetched() time.Time {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	return c.asinfoFetched
+}
+
+// setInvalidTable swaps in a complete new invalids table, along with an
+// index of it by prefix.
+func (c *Client) setInvalidTable(invalids map[int][]*net.IPNet) {
+	byPrefix := make(map[netip.Prefix][]ASN)
+	for asn, prefixes := range invalids {
+		for _, n := range prefixes {
+			if p, ok := ipNetPrefix(n); ok {
+				byPrefix[p] = append(byPrefix[p], ASN(asn))
+			}
+		}
+	}
+	for _, asns := range byPrefix {
+		sort.Slice(asns, func(i, j int) bool { return asns[i] < asns[j] })
+	}
+
+	// The deprecated field gets its own map, slices and prefixes, so
+	// callers changing it can't change the client's table.
+	exported := make(map[int][]*net.IPNet, len(invalids))
+	for asn, prefixes := range invalids {
+		exported[asn] = cloneIPNets(prefixes)
+	}
+
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	c.invalids = invalids
+	c.invalidsByPrefix = byPrefix
+	c.Invalids = exported
+}
+
+// setInvalidCounts swaps in a table of invalid counts for
+// WithInvalidCountsOnly, dropping any full table.
+func (c *Client) setInvalidCounts(counts map[int]int) {
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	c.invalidCounts = counts
+	c.invalidPrefixes = nil
+	c.invalids = nil
+	c.invalidsByPrefix = nil
+	c.Invalids = nil
+}
+
+// addFetchedInvalids keeps the prefixes of asns, fetched on demand for
+// WithInvalidCountsOnly, until the counts are next loaded.
+func (c *Client) addFetchedInvalids(asns []int, invalids map[int][]*net.IPNet) {
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	if c.invalidPrefixes == nil {
+		c.invalidPrefixes = make(map[int][]*net.IPNet, len(asns))
+	}
+	for _, asn := range asns {
+		c.invalidPrefixes[asn] = invalids[asn]
+	}
+}
+
+// fetchedInvalid returns the prefixes of asn kept by addFetchedInvalids,
+// and whether there were any kept. They must not be modified.
+func (c *Client) fetchedInvalid(asn int) ([]*net.IPNet, bool) {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	prefixes, ok := c.invalidPrefixes[asn]
+	return prefixes, ok
+}
+
+// invalidCountTable returns the number of invalid prefixes of each ASN,
+// from whichever invalids table is loaded, or nil if neither is. It must
+// not be modified.
+func (c *Client) invalidCountTable() map[int]int {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	if c.invalidCounts != nil {
+		return c.invalidCounts
+	}
+	if c.invalids == nil {
+		return nil
+	}
+	counts := make(map[int]int, len(c.invalids))
+	for asn, prefixes := range c.invalids {
+		counts[asn] = len(prefixes)
+	}
+	return counts
+}
+
+// InvalidCount returns how many ROA invalid prefixes asn originates, from
+// the table loaded by GetInvalids. It does not query the API, and returns
+// ErrCacheCold if the table has not been loaded.
+func (c *Client) InvalidCount(asn int) (int, error) {
+	counts := c.invalidCountTable()
+	if counts == nil {
+		return 0, lookupError("invalid", fmt.Sprint(asn), ErrCacheCold{Cache: "invalids"})
+	}
+	return counts[asn], nil
+}
+
+// GetInvalidOrigins returns the ASes announcing exactly prefix with an
+// INVALID ROA state, in order, from the table loaded by GetInvalids. It is
+// the inverse of GetInvalid, for investigating a hijacked prefix. It does
+// not query the API, and returns nil if the table has not been loaded.
+func (c *Client) GetInvalidOrigins(prefix netip.Prefix) []ASN {
+	c.tablesMu.RLock()
+	defer c.tablesMu.RUnlock()
+	asns := c.invalidsByPrefix[prefix.Masked()]
+	if asns == nil {
+		return nil
+	}
+	return append([]ASN(nil), asns...)
+}
+
+// ASNameTable returns a copy of the AS name table loaded by GetASNames.
+// It returns nil if GetASNames has not been called.
+func (c *Client) ASNameTable() map[int]string {
+	asinfo := c.asTable()
+	if asinfo == nil {
+		return nil
+	}
+	table := make(map[int]string, len(asinfo))
+	for asn, info := range asinfo {
+		table[int(asn)] = info.ASName
+	}
+	return table
+}
+
+// ASInfoTable returns a copy of the AS table loaded by GetASNames, including
+// each AS's locale. It returns nil if GetASNames has not been called.
+func (c *Client) ASInfoTable() map[uint32]ASNumName {
+	asinfo := c.asTable()
+	if asinfo == nil {
+		return nil
+	}
+	table := make(map[uint32]ASNumName, len(asinfo))
+	for asn, info := range asinfo {
+		table[asn] = info
+	}
+	return table
+}
+
+// GetASInfo returns the name and locale of asn from the table loaded by
+// GetASNames. It does not query the API, and returns false if the AS is not
+// in the table or the table has not been loaded.
+func (c *Client) GetASInfo(asn int) (ASNumName, bool) {
+	info, ok := c.asTable()[uint32(asn)]
+	return info, ok
+}
+
+// InvalidTable returns a copy of the invalids table loaded by GetInvalids,
+// keyed by origin ASN. It returns nil if GetInvalids has not been called.
+// The copy is the caller's to modify.
+func (c *Client) InvalidTable() map[int][]*net.IPNet {
+	invalids := c.invalidTable()
+	if invalids == nil {
+		return nil
+	}
+	table := make(map[int][]*net.IPNet, len(invalids))
+	for asn, prefixes := range invalids {
+		table[asn] = cloneIPNets(prefixes)
+	}
+	return table
+}
+
+// cloneIPNets copies nets down to the address and mask bytes, as a
+// *net.IPNet handed out can otherwise be changed in place.
+func cloneIPNets(nets []*net.IPNet) []*net.IPNet {
+	if nets == nil {
+		return nil
+	}
+	out := make([]*net.IPNet, len(nets))
+	for i, n := range nets {
+		out[i] = &net.IPNet{
+			IP:   append(net.IP(nil), n.IP...),
+			Mask: append(net.IPMask(nil), n.Mask...),
+		}
+	}
+	return out
+}
+
+// InvalidsSummary counts invalid prefixes by the locale of the AS
+// originating them. It needs the tables loaded by GetInvalids and
+// GetASNames; prefixes from an AS with no known locale are counted under
+// the empty string. It returns nil if GetInvalids has not been called.
+func (c *Client) InvalidsSummary() map[string]int {
+	counts := c.invalidCountTable()
+	if counts == nil {
+		return nil
+	}
+	asinfo := c.asTable()
+	summary := make(map[string]int)
+	for asn, count := range counts {
+		summary[asinfo[uint32(asn)].ASLocale] += count
+	}
+	return summary
+}
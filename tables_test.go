@@ -0,0 +1,147 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTablesAreCopies(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/asnames":
+			fmt.Fprint(w, `{"Response":{"ASNames":[{"ASN":3356,"ASName":"LEVEL3","ASLocale":"US"}]}}`)
+		case "/invalids":
+			fmt.Fprint(w, `{"Response":{"Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25"]}]}}`)
+		}
+	})
+	if err := c.GetASNames(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deprecated fields are still filled for callers reading them.
+	if got := c.ASNames[3356]; got != "LEVEL3" {
+		t.Errorf("ASNames[3356] = %q, Want: LEVEL3", got)
+	}
+	if got := len(c.Invalids[13335]); got != 1 {
+		t.Errorf("len(Invalids[13335]) = %d, Want: 1", got)
+	}
+
+	names := c.ASNameTable()
+	names[3356] = "CHANGED"
+	if got := c.ASNameTable()[3356]; got != "LEVEL3" {
+		t.Errorf("Got: %s, Want: LEVEL3", got)
+	}
+
+	if info, ok := c.GetASInfo(3356); !ok || info.ASLocale != "US" {
+		t.Errorf("Got: %+v, Want locale US", info)
+	}
+
+	if got, want := c.InvalidsSummary(), map[string]int{"": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %v, Want: %v", got, want)
+	}
+
+	invalids := c.InvalidTable()
+	invalids[13335][0] = nil
+	if c.InvalidTable()[13335][0] == nil {
+		t.Error("modifying InvalidTable result changed the client table")
+	}
+
+	// Nor can the prefixes themselves be changed in place.
+	prefixes, err := c.GetInvalid(13335)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := prefixes[0].String()
+	prefixes[0].IP[0] = 0
+	c.Invalids[13335][0] = nil
+	if got, _ := c.GetInvalid(13335); got[0].String() != want {
+		t.Errorf("Got: %s after modifying a returned prefix, Want: %s", got[0], want)
+	}
+
+	// Or through the deprecated field.
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	c.Invalids[13335][0].IP[0] = 0
+	if got, _ := c.GetInvalid(13335); got[0].String() != want {
+		t.Errorf("Got: %s after modifying Invalids, Want: %s", got[0], want)
+	}
+	if got := c.GetInvalidOrigins(netip.MustParsePrefix(want)); len(got) != 1 || got[0] != 13335 {
+		t.Errorf("GetInvalidOrigins(%s) = %v after modifying Invalids, Want: [AS13335]", want, got)
+	}
+}
+
+func TestTableRefreshIsAtomic(t *testing.T) {
+	t.Parallel()
+	const n = 500
+	var names strings.Builder
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			names.WriteString(",")
+		}
+		fmt.Fprintf(&names, `{"ASN":%d,"ASName":"AS-%d","ASLocale":"US"}`, i, i)
+	}
+	body := `{"Response":{"ASNames":[` + names.String() + `]}}`
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}, WithLimiter(&countingLimiter{}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if err := c.GetASNames(NoCache()); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if got := len(c.ASInfoTable()); got != 0 && got != n {
+			t.Fatalf("Got a table of %d entries, Want 0 or %d", got, n)
+		}
+	}
+}
+
+func TestGetInvalidOrigins(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Response":{"Invalids":[`+
+			`{"ASN":"64501","Prefixes":["192.0.2.0/24","198.51.100.0/24"]},`+
+			`{"ASN":"64500","Prefixes":["192.0.2.0/24"]}]}}`)
+	})
+	if got := c.GetInvalidOrigins(netip.MustParsePrefix("192.0.2.0/24")); got != nil {
+		t.Errorf("Got: %v before the table was loaded, Want nil", got)
+	}
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   []ASN
+	}{
+		{prefix: "192.0.2.0/24", want: []ASN{64500, 64501}},
+		{prefix: "198.51.100.0/24", want: []ASN{64501}},
+		{prefix: "192.0.2.0/25"},
+	}
+	for _, tc := range tests {
+		got := c.GetInvalidOrigins(netip.MustParsePrefix(tc.prefix))
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Got: %v, Want: %v", tc.prefix, got, tc.want)
+		}
+	}
+}
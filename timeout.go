@@ -0,0 +1,87 @@
+package bgpstuff
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultTimeout is how long a request may take unless the call or an
+// adaptive timeout says otherwise.
+const defaultTimeout = 8 * time.Second
+
+const (
+	latencySamples    = 100
+	minLatencySamples = 10
+)
+
+// WithAdaptiveTimeout sets each request's timeout from how long recent
+// requests to the same endpoint took: the 99th percentile of the last 100
+// successful requests, multiplied by factor and kept between floor and
+// ceiling. Slow endpoints such as /asnames then aren't cut off early, and a
+// hung /route request is given up on sooner. Until an endpoint has seen 10
+// requests, ceiling is used. WithTimeoutOpt still overrides it for a call.
+func WithAdaptiveTimeout(factor float64, floor, ceiling time.Duration) Option {
+	return func(c *Client) {
+		c.latency = &latencyTracker{
+			factor:    factor,
+			floor:     floor,
+			ceiling:   ceiling,
+			endpoints: make(map[string]*latencyRing),
+		}
+	}
+}
+
+// latencyTracker keeps the recent request durations of each endpoint.
+type latencyTracker struct {
+	factor         float64
+	floor, ceiling time.Duration
+
+	mu        sync.Mutex
+	endpoints map[string]*latencyRing
+}
+
+type latencyRing struct {
+	samples []time.Duration
+	next    int
+}
+
+func (lt *latencyTracker) record(endpoint string, took time.Duration) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	r, ok := lt.endpoints[endpoint]
+	if !ok {
+		r = &latencyRing{}
+		lt.endpoints[endpoint] = r
+	}
+	if len(r.samples) < latencySamples {
+		r.samples = append(r.samples, took)
+		return
+	}
+	r.samples[r.next] = took
+	r.next = (r.next + 1) % latencySamples
+}
+
+// timeout returns the timeout for the next request to endpoint.
+func (lt *latencyTracker) timeout(endpoint string) time.Duration {
+	lt.mu.Lock()
+	var sorted []time.Duration
+	if r, ok := lt.endpoints[endpoint]; ok && len(r.samples) >= minLatencySamples {
+		sorted = append(sorted, r.samples...)
+	}
+	lt.mu.Unlock()
+	if sorted == nil {
+		return lt.ceiling
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	p99 := sorted[(len(sorted)*99-1)/100]
+	timeout := time.Duration(float64(p99) * lt.factor)
+	if timeout < lt.floor {
+		return lt.floor
+	}
+	if timeout > lt.ceiling {
+		return lt.ceiling
+	}
+	return timeout
+}
@@ -0,0 +1,53 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	t.Parallel()
+	var slow int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			time.Sleep(500 * time.Millisecond)
+		}
+		fakeAPI(w, r)
+	}, WithAdaptiveTimeout(3, 50*time.Millisecond, time.Second))
+
+	lt := c.latency
+	if got := lt.timeout("route"); got != time.Second {
+		t.Errorf("Got: %v with no history, Want the ceiling", got)
+	}
+	for i := 0; i < minLatencySamples; i++ {
+		if _, err := c.GetRoute("1.1.1.1", NoCache()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := lt.timeout("route"); got < 50*time.Millisecond || got >= time.Second {
+		t.Errorf("Got: %v after fast requests, Want near the floor", got)
+	}
+
+	// A request far slower than the history is given up on.
+	atomic.StoreInt32(&slow, 1)
+	if _, err := c.GetRoute("1.1.1.1", NoCache()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Got: %v, Want: %v", err, context.DeadlineExceeded)
+	}
+	// Unless the call sets its own timeout.
+	if _, err := c.GetRoute("1.1.1.1", NoCache(), WithTimeoutOpt(2*time.Second)); err != nil {
+		t.Errorf("No error expected, but got error: %v", err)
+	}
+
+	lt = &latencyTracker{factor: 2, floor: time.Millisecond, ceiling: time.Hour, endpoints: make(map[string]*latencyRing)}
+	for i := 1; i <= 200; i++ {
+		lt.record("asnames", time.Duration(i)*time.Millisecond)
+	}
+	// Only the last 100 are kept, so the 99th percentile is 199ms.
+	if got, want := lt.timeout("asnames"), 398*time.Millisecond; got != want {
+		t.Errorf("Got: %v, Want: %v", got, want)
+	}
+}
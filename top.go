@@ -0,0 +1,64 @@
+package bgpstuff
+
+import "sort"
+
+// ASNCount is an AS and a count of prefixes, as ranked by the Top methods.
+type ASNCount struct {
+	ASN ASN
+	// Name is the AS name from the table loaded by GetASNames, if any.
+	Name  string
+	Count int
+}
+
+// TopInvalidASNs returns the n ASes originating the most ROA invalid
+// prefixes, from the table loaded by GetInvalids, most first. n of zero or
+// less returns every AS. It returns nil if GetInvalids has not been called.
+func (c *Client) TopInvalidASNs(n int) []ASNCount {
+	counts := c.invalidCountTable()
+	if counts == nil {
+		return nil
+	}
+	return c.rank(counts, n)
+}
+
+// TopSourcingASNs returns the n ASes sourcing the most prefixes, most
+// first, among the ASes looked up with GetSourced. n of zero or less
+// returns every AS.
+func (c *Client) TopSourcingASNs(n int) []ASNCount {
+	c.tablesMu.RLock()
+	counts := make(map[int]int, len(c.sourced))
+	for asn, count := range c.sourced {
+		counts[asn] = count
+	}
+	c.tablesMu.RUnlock()
+	return c.rank(counts, n)
+}
+
+// recordSourced remembers how many prefixes asn sources.
+func (c *Client) recordSourced(asn, count int) {
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	if c.sourced == nil {
+		c.sourced = make(map[int]int)
+	}
+	c.sourced[asn] = count
+}
+
+// rank sorts counts by count, then ASN, and keeps the first n.
+func (c *Client) rank(counts map[int]int, n int) []ASNCount {
+	asinfo := c.asTable()
+	ranked := make([]ASNCount, 0, len(counts))
+	for asn, count := range counts {
+		ranked = append(ranked, ASNCount{ASN: ASN(asn), Name: asinfo[uint32(asn)].ASName, Count: count})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].ASN < ranked[j].ASN
+	})
+	if n > 0 && len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
@@ -0,0 +1,50 @@
+package bgpstuff
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTopASNs(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/invalids":
+			fmt.Fprint(w, `{"Response":{"Invalids":[`+
+				`{"ASN":"64500","Prefixes":["192.0.2.0/24"]},`+
+				`{"ASN":"64501","Prefixes":["198.51.100.0/24","203.0.113.0/24"]},`+
+				`{"ASN":"64502","Prefixes":["192.0.2.0/25"]}]}}`)
+		case "/asnames":
+			fmt.Fprint(w, `{"Response":{"ASNames":[{"ASN":64501,"ASName":"EXAMPLE","ASLocale":"ZZ"}]}}`)
+		case "/sourced/13335":
+			fmt.Fprint(w, `{"Response":{"Sourced":{"Ipv4":2,"Ipv6":1,"Prefixes":["1.1.1.0/24","1.0.0.0/24","2606:4700::/32"]}}}`)
+		case "/sourced/15169":
+			fmt.Fprint(w, `{"Response":{"Sourced":{"Ipv4":1,"Ipv6":0,"Prefixes":["8.8.8.0/24"]}}}`)
+		}
+	})
+	if got := c.TopInvalidASNs(2); got != nil {
+		t.Errorf("Got: %v before the table was loaded, Want nil", got)
+	}
+	if err := c.GetInvalids(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetASNames(); err != nil {
+		t.Fatal(err)
+	}
+	want := []ASNCount{{ASN: 64501, Name: "EXAMPLE", Count: 2}, {ASN: 64500, Count: 1}}
+	if got := c.TopInvalidASNs(2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %v, Want: %v", got, want)
+	}
+
+	for _, asn := range []int{15169, 13335} {
+		if _, _, _, err := c.GetSourced(asn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want = []ASNCount{{ASN: 13335, Count: 3}, {ASN: 15169, Count: 1}}
+	if got := c.TopSourcingASNs(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %v, Want: %v", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package bgpstuff
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithAPIAddr(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(fakeAPI))
+	t.Cleanup(srv.Close)
+
+	// The hostname doesn't resolve, so the request only works if the
+	// pinned address is used.
+	c := NewBGPClient(true, WithAPIAddr(srv.Listener.Addr().String()))
+	c.api = "http://bgpstuff.invalid"
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Errorf("No error expected, but got error: %v", err)
+	}
+}
+
+func TestWithNetwork(t *testing.T) {
+	t.Parallel()
+	// The test server only listens on IPv4, so forcing IPv6 must fail.
+	c := newTestClient(t, fakeAPI, WithNetwork("tcp4"))
+	if _, err := c.GetOrigin("1.1.1.1"); err != nil {
+		t.Errorf("No error expected, but got error: %v", err)
+	}
+	c = newTestClient(t, fakeAPI, WithNetwork("tcp6"))
+	if _, err := c.GetOrigin("1.1.1.1"); err == nil {
+		t.Error("Expected error, but no error returned")
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	t.Parallel()
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := parseProxyAuth(r.Header.Get("Proxy-Authorization"))
+		if !ok || user != "user" || pass != "secret" {
+			http.Error(w, "proxy auth required", http.StatusProxyAuthRequired)
+			return
+		}
+		if r.URL.Host != "bgpstuff.invalid" {
+			http.Error(w, "unexpected host", http.StatusBadGateway)
+			return
+		}
+		atomic.AddInt32(&proxied, 1)
+		fakeAPI(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+
+	u, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.User = url.UserPassword("user", "secret")
+	// The API hostname doesn't resolve, so the request only works if it
+	// goes through the proxy.
+	c := NewBGPClient(true, WithProxy(u))
+	c.api = "http://bgpstuff.invalid"
+	origin, err := c.GetOrigin("1.1.1.1")
+	if err != nil || origin != 13335 {
+		t.Errorf("Got: %d %v, Want: 13335", origin, err)
+	}
+	if atomic.LoadInt32(&proxied) != 1 {
+		t.Error("Request did not go through the proxy")
+	}
+}
+
+func parseProxyAuth(header string) (user, pass string, ok bool) {
+	r := &http.Request{Header: http.Header{"Authorization": {header}}}
+	return r.BasicAuth()
+}
+
+func TestConnectionReuse(t *testing.T) {
+	t.Parallel()
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(fakeAPI))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c := NewBGPClient(true, WithIdleConns(4, time.Minute))
+	c.api = srv.URL
+	for i := 0; i < 5; i++ {
+		if _, err := c.GetOrigin("1.1.1.1", NoCache()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Errorf("Got %d connections, Want 1", got)
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewTLSServer(http.HandlerFunc(fakeAPI))
+	t.Cleanup(srv.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "system roots", wantErr: true},
+		{name: "private CA", opts: []Option{WithTLSConfig(&tls.Config{RootCAs: pool})}},
+		{name: "insecure", opts: []Option{WithInsecureSkipVerify()}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewBGPClient(true, tc.opts...)
+			c.api = srv.URL
+			_, err := c.GetOrigin("1.1.1.1")
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Got error: %v, Want error: %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	t.Parallel()
+	var gotPath, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotKey = r.URL.EscapedPath(), r.URL.Query().Get("key")
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/bgpstuff")
+		fakeAPI(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		base, key string
+	}{
+		{base: srv.URL + "/bgpstuff/"},
+		{base: srv.URL + "/bgpstuff"},
+		{base: srv.URL + "/bgpstuff/?key=abc", key: "abc"},
+	}
+	for _, tc := range tests {
+		c := NewBGPClient(true, WithBaseURL(tc.base))
+		origin, err := c.GetOrigin("1.1.1.1")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.base, err)
+		}
+		if origin != 13335 || gotPath != "/bgpstuff/origin/1.1.1.1" || gotKey != tc.key {
+			t.Errorf("%s: Got %d from %s?key=%s, Want: 13335 from /bgpstuff/origin/1.1.1.1?key=%s", tc.base, origin, gotPath, gotKey, tc.key)
+		}
+	}
+
+	// Arguments are escaped rather than adding to the path.
+	c := NewBGPClient(true, WithBaseURL(srv.URL+"/bgpstuff"))
+	if _, err := Get[struct{}](c, "peers", "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/bgpstuff/peers/a%2Fb"; gotPath != want {
+		t.Errorf("Got path: %s, Want: %s", gotPath, want)
+	}
+}
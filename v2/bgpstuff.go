@@ -0,0 +1,167 @@
+// Package bgpstuff is version 2 of the bgpstuff.net client.
+//
+// It makes the breaking changes v1 could not: methods take a context
+// first, addresses and prefixes are net/netip types, lookups return result
+// structs rather than several loose values, and the client is used through
+// the Lookuper interface where that is all a caller needs. No settings are
+// exported fields, so none can be changed under a running client.
+//
+// v2 is built on the v1 client, which stays maintained, and the two share
+// types wherever v1's were already right: options, call options, errors,
+// ASN, RouteResult, ASPath, ROAResult and Meta are the same types in both,
+// so code can move over a call at a time. Client.V1 returns the underlying
+// v1 client for anything v2 does not cover yet.
+package bgpstuff
+
+import (
+	"context"
+	"net/netip"
+
+	v1 "github.com/mellowdrifter/go-bgpstuff.net"
+)
+
+// Types shared with v1.
+type (
+	Option      = v1.Option
+	CallOption  = v1.CallOption
+	ASN         = v1.ASN
+	RouteResult = v1.RouteResult
+	ASPath      = v1.ASPath
+	ROAResult   = v1.ROAResult
+	ROAStatus   = v1.ROAStatus
+	Meta        = v1.Meta
+)
+
+// Errors shared with v1, so errors.Is works against either.
+var (
+	ErrInvalidIP           = v1.ErrInvalidIP
+	ErrInvalidASN          = v1.ErrInvalidASN
+	ErrResponseTooLarge    = v1.ErrResponseTooLarge
+	ErrClientClosed        = v1.ErrClientClosed
+	ErrWouldExceedDeadline = v1.ErrWouldExceedDeadline
+)
+
+// Lookuper answers the basic IP and AS lookups. *Client is one.
+type Lookuper interface {
+	Route(ctx context.Context, addr netip.Addr, opts ...CallOption) (RouteResult, error)
+	Origin(ctx context.Context, addr netip.Addr, opts ...CallOption) (ASN, error)
+	ASName(ctx context.Context, asn ASN, opts ...CallOption) (string, error)
+}
+
+var _ Lookuper = (*Client)(nil)
+
+// Client is a bgpstuff.net client. It is safe for concurrent use.
+type Client struct {
+	c *v1.Client
+}
+
+// New returns a client for bgpstuff.net. Any v1 option may be given; use
+// WithBaseURL to point it elsewhere, such as at a test server.
+func New(opts ...Option) *Client {
+	return &Client{c: v1.NewBGPClient(false, opts...)}
+}
+
+// V1 returns the v1 client this client is built on. It shares the cache,
+// rate limit and connections.
+func (c *Client) V1() *v1.Client {
+	return c.c
+}
+
+func withContext(ctx context.Context, opts []CallOption) []CallOption {
+	return append([]CallOption{v1.WithContext(ctx)}, opts...)
+}
+
+// Route returns the route covering addr.
+func (c *Client) Route(ctx context.Context, addr netip.Addr, opts ...CallOption) (RouteResult, error) {
+	res, err := c.c.GetRouteResult(addr.String(), withContext(ctx, opts)...)
+	if err != nil {
+		return RouteResult{}, err
+	}
+	return *res, nil
+}
+
+// Origin returns the AS originating the route covering addr, or 0 if there
+// is none.
+func (c *Client) Origin(ctx context.Context, addr netip.Addr, opts ...CallOption) (ASN, error) {
+	asn, err := c.c.GetOrigin(addr.String(), withContext(ctx, opts)...)
+	return ASN(asn), err
+}
+
+// ASPath returns the AS path to addr, or nil if there is none.
+func (c *Client) ASPath(ctx context.Context, addr netip.Addr, opts ...CallOption) (*ASPath, error) {
+	return c.c.GetASPathDetail(addr.String(), withContext(ctx, opts)...)
+}
+
+// ROA returns the RPKI state of the route covering addr, or nil if there
+// is no route.
+func (c *Client) ROA(ctx context.Context, addr netip.Addr, opts ...CallOption) (*ROAResult, error) {
+	return c.c.GetROADetail(addr.String(), withContext(ctx, opts)...)
+}
+
+// ASName returns the name of asn, or "" if it has none.
+func (c *Client) ASName(ctx context.Context, asn ASN, opts ...CallOption) (string, error) {
+	return c.c.GetASName(int(asn), withContext(ctx, opts)...)
+}
+
+// Sourced is the set of prefixes an AS originates.
+type Sourced struct {
+	Prefixes   []netip.Prefix
+	IPv4, IPv6 int
+}
+
+// Sourced returns the prefixes originated by asn.
+func (c *Client) Sourced(ctx context.Context, asn ASN, opts ...CallOption) (Sourced, error) {
+	nets, v4, v6, err := c.c.GetSourced(int(asn), withContext(ctx, opts)...)
+	if err != nil {
+		return Sourced{}, err
+	}
+	s := Sourced{Prefixes: make([]netip.Prefix, 0, len(nets)), IPv4: v4, IPv6: v6}
+	for _, n := range nets {
+		p, err := netip.ParsePrefix(n.String())
+		if err != nil {
+			return Sourced{}, err
+		}
+		s.Prefixes = append(s.Prefixes, p)
+	}
+	return s, nil
+}
+
+// TableSize is the number of prefixes in the global table.
+type TableSize struct {
+	IPv4, IPv6 int
+}
+
+// Totals returns the size of the global table.
+func (c *Client) Totals(ctx context.Context, opts ...CallOption) (TableSize, error) {
+	v4, v6, err := c.c.GetTotals(withContext(ctx, opts)...)
+	return TableSize{IPv4: v4, IPv6: v6}, err
+}
+
+// Invalids returns every ROA invalid prefix in the global table, keyed by
+// origin.
+func (c *Client) Invalids(ctx context.Context, opts ...CallOption) (map[ASN][]netip.Prefix, error) {
+	if err := c.c.GetInvalids(withContext(ctx, opts)...); err != nil {
+		return nil, err
+	}
+	invalids := make(map[ASN][]netip.Prefix)
+	for asn, nets := range c.c.InvalidTable() {
+		for _, n := range nets {
+			p, err := netip.ParsePrefix(n.String())
+			if err != nil {
+				return nil, err
+			}
+			invalids[ASN(asn)] = append(invalids[ASN(asn)], p)
+		}
+	}
+	return invalids, nil
+}
+
+// Close stops the client, as v1's Client.Close does.
+func (c *Client) Close() error {
+	return c.c.Close()
+}
+
+// Shutdown stops the client, as v1's Client.Shutdown does.
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.c.Shutdown(ctx)
+}
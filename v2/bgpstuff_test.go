@@ -0,0 +1,53 @@
+package bgpstuff_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/mellowdrifter/go-bgpstuff.net/bgpstufftest"
+	"github.com/mellowdrifter/go-bgpstuff.net/v2"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	srv := bgpstufftest.StartLocalServer(t, bgpstufftest.DefaultFixture())
+	c := bgpstuff.New(bgpstuff.WithBaseURL(srv.URL), bgpstuff.WithAllowPrivate())
+	t.Cleanup(func() { c.Close() })
+	ctx := context.Background()
+	addr := netip.MustParseAddr("1.1.1.1")
+
+	route, err := c.Route(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.Prefix != netip.MustParsePrefix("1.1.1.0/24") {
+		t.Errorf("Got route: %v, Want 1.1.1.0/24", route.Prefix)
+	}
+	if origin, err := c.Origin(ctx, addr); err != nil || origin != 13335 {
+		t.Errorf("Got origin: %v, %v, Want AS13335", origin, err)
+	}
+	if name, err := c.ASName(ctx, 15169); err != nil || name != "GOOGLE" {
+		t.Errorf("Got name: %q, %v, Want GOOGLE", name, err)
+	}
+	sourced, err := c.Sourced(ctx, 13335)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sourced.Prefixes) != 2 || sourced.IPv4 != 1 || sourced.IPv6 != 1 {
+		t.Errorf("Got sourced: %+v, Want one IPv4 and one IPv6 prefix", sourced)
+	}
+	invalids, err := c.Invalids(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := invalids[4134]; len(got) != 1 || got[0] != netip.MustParsePrefix("23.1.0.0/16") {
+		t.Errorf("Got invalids: %v, Want 23.1.0.0/16 from AS4134", invalids)
+	}
+
+	// Errors are v1's, so either package's can be checked against.
+	if _, err := c.Origin(ctx, netip.Addr{}); !errors.Is(err, bgpstuff.ErrInvalidIP) {
+		t.Errorf("Got: %v, Want: %v", err, bgpstuff.ErrInvalidIP)
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/mellowdrifter/go-bgpstuff.net/v2
+
+go 1.18
+
+// v2 is built on the v1 client, and needs what it gained in v1.1.0. That
+// must be tagged before v2 is.
+require github.com/mellowdrifter/go-bgpstuff.net v1.1.0
+
+require (
+	github.com/mellowdrifter/bogons v1.0.0 // indirect
+	golang.org/x/time v0.5.0 // indirect
+)
+
+// Work against the v1 client in the parent directory. This only applies
+// when building v2 from this repository; users of v2 get the tagged v1.
+replace github.com/mellowdrifter/go-bgpstuff.net => ../
@@ -0,0 +1,14 @@
+package bgpstuff
+
+import v1 "github.com/mellowdrifter/go-bgpstuff.net"
+
+// The options most clients need. Every other v1 option works too.
+
+// WithBaseURL sends requests to u instead of bgpstuff.net.
+func WithBaseURL(u string) Option { return v1.WithBaseURL(u) }
+
+// WithAllowPrivate allows lookups of private and reserved addresses.
+func WithAllowPrivate() Option { return v1.WithAllowPrivate() }
+
+// WithMeta has the call fill m with details of how it was answered.
+func WithMeta(m *Meta) CallOption { return v1.WithMeta(m) }
@@ -0,0 +1,139 @@
+package bgpstuff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// ErrNoVRPs is returned by CheckROA if no VRPs have been set.
+var ErrNoVRPs = errors.New("no VRPs loaded, call SetVRPs first")
+
+// VRP is a validated ROA payload: ASN may originate Prefix, and any more
+// specific of it up to MaxLength.
+type VRP struct {
+	Prefix    netip.Prefix
+	MaxLength int
+	ASN       ASN
+}
+
+type vrpFile struct {
+	ROAs []struct {
+		Prefix    string          `json:"prefix"`
+		MaxLength int             `json:"maxLength"`
+		ASN       json.RawMessage `json:"asn"`
+	} `json:"roas"`
+}
+
+// LoadVRPs reads the VRPs at path, in the JSON format written by
+// rpki-client, Routinator and OctoRPKI. ASNs may be numbers or strings
+// such as "AS13335".
+func LoadVRPs(path string) ([]VRP, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f vrpFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("vrps %s: %w", path, err)
+	}
+
+	vrps := make([]VRP, 0, len(f.ROAs))
+	for _, roa := range f.ROAs {
+		prefix, err := netip.ParsePrefix(roa.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("vrps %s: %w", path, err)
+		}
+		asn, err := parseVRPASN(roa.ASN)
+		if err != nil {
+			return nil, fmt.Errorf("vrps %s: %s: %w", path, roa.Prefix, err)
+		}
+		maxLength := roa.MaxLength
+		if maxLength == 0 {
+			maxLength = prefix.Bits()
+		}
+		if maxLength < prefix.Bits() || maxLength > prefix.Addr().BitLen() {
+			return nil, fmt.Errorf("vrps %s: bad maxLength %d for %s", path, maxLength, prefix)
+		}
+		vrps = append(vrps, VRP{Prefix: prefix.Masked(), MaxLength: maxLength, ASN: asn})
+	}
+	return vrps, nil
+}
+
+func parseVRPASN(raw json.RawMessage) (ASN, error) {
+	asn, err := ParseASN(strings.Trim(string(raw), `"`))
+	if err != nil {
+		return 0, fmt.Errorf("bad asn %s", raw)
+	}
+	return asn, nil
+}
+
+// SetVRPs replaces the VRPs CheckROA validates against.
+func (c *Client) SetVRPs(vrps []VRP) {
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	c.loadedVRPs = make([]VRP, len(vrps))
+	copy(c.loadedVRPs, vrps)
+	c.indexVRPs()
+}
+
+// indexVRPs rebuilds the VRPs CheckROA validates against from those set
+// with SetVRPs and any SLURM file applied: VRPs matched by a filter are
+// removed and assertions added, as RFC 8416 says, before anything is
+// validated. c.tablesMu must be held.
+func (c *Client) indexVRPs() {
+	if c.loadedVRPs == nil {
+		c.vrps = nil
+		return
+	}
+	vrps := c.loadedVRPs
+	if c.slurm != nil {
+		vrps = c.slurm.apply(vrps)
+	}
+	index := make(map[netip.Prefix][]VRP, len(vrps))
+	for _, v := range vrps {
+		p := v.Prefix.Masked()
+		index[p] = append(index[p], v)
+	}
+	c.vrps = index
+}
+
+// CheckROA returns the RPKI validation state of prefix announced by
+// origin, following RFC 6811, against the VRPs set with SetVRPs. Unlike
+// GetROA it checks the exact prefix rather than the route covering an
+// address, so it can tell whether a more specific would be valid before it
+// is announced. Any SLURM file applied with ApplySLURM changes the VRPs
+// validated against. It does not query the API. An invalid prefix, such as
+// the zero netip.Prefix, returns ErrInvalidIP.
+func (c *Client) CheckROA(prefix netip.Prefix, origin ASN) (ROAStatus, error) {
+	if !prefix.IsValid() {
+		return "", lookupError("roa", prefix.String(), ErrInvalidIP)
+	}
+	c.tablesMu.RLock()
+	vrps := c.vrps
+	c.tablesMu.RUnlock()
+	if vrps == nil {
+		return "", ErrNoVRPs
+	}
+	prefix = prefix.Masked()
+
+	status := ROAUnknown
+	for bits := prefix.Bits(); bits >= 0; bits-- {
+		p, _ := prefix.Addr().Prefix(bits)
+		for _, v := range vrps[p] {
+			// An origin of AS0 can never be valid (RFC 6483).
+			if v.ASN == origin && origin != 0 && prefix.Bits() <= v.MaxLength {
+				status = ROAValid
+				break
+			}
+			status = ROAInvalid
+		}
+		if status == ROAValid {
+			break
+		}
+	}
+	return status, nil
+}
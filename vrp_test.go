@@ -0,0 +1,94 @@
+package bgpstuff
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckROA(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vrps.json")
+	vrps := `{"metadata": {}, "roas": [
+  {"asn": "AS13335", "prefix": "1.1.1.0/24", "maxLength": 24, "ta": "apnic"},
+  {"asn": "AS13335", "prefix": "1.0.0.0/8", "maxLength": 24, "ta": "apnic"},
+  {"asn": 64496, "prefix": "192.0.2.0/23", "maxLength": 24, "ta": "arin"},
+  {"asn": "AS0", "prefix": "203.0.113.0/24", "maxLength": 32, "ta": "apnic"},
+  {"asn": "AS13335", "prefix": "2606:4700::/32", "maxLength": 48, "ta": "arin"}
+]}`
+	if err := os.WriteFile(path, []byte(vrps), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, fakeAPI)
+	if _, err := c.CheckROA(netip.Prefix{}, 13335); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("Got: %v, Want: %v", err, ErrInvalidIP)
+	}
+	if _, err := c.CheckROA(netip.MustParsePrefix("1.1.1.0/24"), 13335); !errors.Is(err, ErrNoVRPs) {
+		t.Errorf("Got: %v, Want: %v", err, ErrNoVRPs)
+	}
+	loaded, err := LoadVRPs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetVRPs(loaded)
+
+	tests := []struct {
+		prefix string
+		origin ASN
+		want   ROAStatus
+	}{
+		{"1.1.1.0/24", 13335, ROAValid},
+		// A more specific than the maxLength allows.
+		{"1.1.1.0/25", 13335, ROAInvalid},
+		{"1.1.1.0/24", 64500, ROAInvalid},
+		{"192.0.2.0/23", 64496, ROAValid},
+		{"192.0.3.0/24", 64496, ROAValid},
+		{"203.0.113.0/24", 0, ROAInvalid},
+		{"2606:4700:10::/48", 13335, ROAValid},
+		{"8.8.8.0/24", 15169, ROAUnknown},
+	}
+	for _, tc := range tests {
+		got, err := c.CheckROA(netip.MustParsePrefix(tc.prefix), tc.origin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s from %s: Got: %s, Want: %s", tc.prefix, tc.origin, got, tc.want)
+		}
+	}
+
+	// SLURM changes the VRPs before validation, so a filtered VRP leaves
+	// any other covering VRP in force.
+	slurm := filepath.Join(t.TempDir(), "slurm.json")
+	if err := os.WriteFile(slurm, []byte(`{"slurmVersion": 1,
+  "validationOutputFilters": {"prefixFilters": [{"prefix": "1.1.1.0/24"}]},
+  "locallyAddedAssertions": {"prefixAssertions": [{"asn": 64500, "prefix": "1.1.1.0/24", "maxPrefixLength": 25}]}
+}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplySLURM(slurm); err != nil {
+		t.Fatal(err)
+	}
+	tests = []struct {
+		prefix string
+		origin ASN
+		want   ROAStatus
+	}{
+		{"1.1.1.0/24", 13335, ROAValid},
+		{"1.1.1.0/25", 64500, ROAValid},
+		{"1.1.1.0/25", 13335, ROAInvalid},
+		{"1.1.1.0/26", 64500, ROAInvalid},
+		{"8.8.8.0/24", 15169, ROAUnknown},
+	}
+	for _, tc := range tests {
+		got, err := c.CheckROA(netip.MustParsePrefix(tc.prefix), tc.origin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("With SLURM, %s from %s: Got: %s, Want: %s", tc.prefix, tc.origin, got, tc.want)
+		}
+	}
+}
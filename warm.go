@@ -0,0 +1,74 @@
+package bgpstuff
+
+import (
+	"context"
+	"io"
+)
+
+// WithProgress has fn called as the bodies of the big downloads, the AS
+// names and the invalids, are read. done is the bytes read so far, and
+// total the size of the body, or -1 if the server didn't say. It lets a CLI
+// draw a progress bar, or a daemon log how far it is through startup. fn
+// is called from the goroutine doing the download.
+func WithProgress(fn func(done, total int64)) Option {
+	return func(c *Client) {
+		c.progress = fn
+	}
+}
+
+// Warm loads the AS names and invalids tables, so lookups that use them
+// are ready before the first one is made. It stops early if ctx is done.
+func (c *Client) Warm(ctx context.Context) error {
+	if err := c.GetASNames(WithContext(ctx)); err != nil {
+		return err
+	}
+	return c.GetInvalids(WithContext(ctx))
+}
+
+// EnsureASNames loads the AS names table with GetASNames if it is not
+// already loaded. Callers racing to load it wait for the first, rather than
+// each downloading it.
+func (c *Client) EnsureASNames(ctx context.Context) error {
+	c.ensureMu.Lock()
+	defer c.ensureMu.Unlock()
+	if c.asTable() != nil {
+		return nil
+	}
+	return c.GetASNames(WithContext(ctx))
+}
+
+// EnsureInvalids loads the invalids table with GetInvalids if it is not
+// already loaded, so that methods such as GetInvalid don't return
+// ErrCacheCold. Callers racing to load it wait for the first, rather than
+// each downloading it.
+func (c *Client) EnsureInvalids(ctx context.Context) error {
+	c.ensureMu.Lock()
+	defer c.ensureMu.Unlock()
+	if c.invalidCountTable() != nil {
+		return nil
+	}
+	return c.GetInvalids(WithContext(ctx))
+}
+
+// progressEndpoints are the endpoints WithProgress reports on.
+var progressEndpoints = map[string]bool{
+	"asnames":  true,
+	"invalids": true,
+}
+
+// progressReader reports how much of r has been read to fn.
+type progressReader struct {
+	r     io.Reader
+	done  int64
+	total int64
+	fn    func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.done += int64(n)
+		p.fn(p.done, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,78 @@
+package bgpstuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWarmProgress(t *testing.T) {
+	t.Parallel()
+	var finished []int64
+	bodies := map[string]string{
+		"/asnames":  `{"Response":{"Action":"asnames","ASNames":[{"ASN":13335,"ASName":"CLOUDFLARENET","ASLocale":"US"},{"ASN":15169,"ASName":"GOOGLE","ASLocale":"US"}]}}`,
+		"/invalids": `{"Response":{"Action":"invalids","Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25"]}]}}`,
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(bodies[r.URL.Path])))
+		fmt.Fprint(w, bodies[r.URL.Path])
+	}, WithProgress(func(done, total int64) {
+		if done > total {
+			t.Errorf("Got progress %d of %d", done, total)
+		}
+		if done == total {
+			finished = append(finished, total)
+		}
+	}))
+	if err := c.Warm(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{int64(len(bodies["/asnames"])), int64(len(bodies["/invalids"]))}
+	if !reflect.DeepEqual(finished, want) {
+		t.Errorf("Got finished downloads of %v bytes, Want: %v", finished, want)
+	}
+	if name, _ := c.GetASName(13335); name != "CLOUDFLARENET" {
+		t.Errorf("Got: %q, Want: CLOUDFLARENET", name)
+	}
+	if got, _ := c.GetInvalid(13335); len(got) != 1 {
+		t.Errorf("Got invalids: %v, Want one", got)
+	}
+}
+
+func TestEnsureInvalids(t *testing.T) {
+	t.Parallel()
+	var requests int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"Response":{"Invalids":[{"ASN":"13335","Prefixes":["1.1.1.0/25"]}]}}`)
+	})
+
+	_, err := c.GetInvalid(13335)
+	var cold ErrCacheCold
+	if !errors.As(err, &cold) || cold.Cache != "invalids" {
+		t.Fatalf("Got error: %v, Want: ErrCacheCold for invalids", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.EnsureInvalids(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Got %d requests, Want: 1", got)
+	}
+	if got, err := c.GetInvalid(13335); err != nil || len(got) != 1 {
+		t.Errorf("Got: %v, %v, Want one prefix", got, err)
+	}
+}